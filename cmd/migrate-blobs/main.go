@@ -0,0 +1,113 @@
+// Command migrate-blobs copies photo files from one storage backend to
+// another, keeping their ids, so that switching PHOTO_STORAGE does not break
+// the file ids stored in Postgres. Both backends are configured from the same
+// environment as the service (MONGO_URI, PHOTO_STORAGE_DIR, S3_*); -from and
+// -to pick which ones to use:
+//
+//	go run ./cmd/migrate-blobs -from gridfs -to s3
+//
+// Files already present in the destination are skipped, so an interrupted run
+// can simply be restarted. The source is left untouched; switch PHOTO_STORAGE
+// to the destination once the copy is complete.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/joho/godotenv"
+
+	"listing-service/internal/storage"
+)
+
+func main() {
+	from := flag.String("from", "", "source backend: gridfs, fs or s3")
+	to := flag.String("to", "", "destination backend: gridfs, fs or s3")
+	flag.Parse()
+	if *from == "" || *to == "" || *from == *to {
+		log.Fatal("-from and -to must name two different backends")
+	}
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: no .env file found, using environment variables")
+	}
+	cfg, err := storage.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Invalid photo storage config: %v", err)
+	}
+
+	ctx := context.Background()
+	src := open(ctx, cfg, *from)
+	dst := open(ctx, cfg, *to)
+
+	var copied, skipped int
+	err = src.Walk(ctx, func(id string) error {
+		done, err := copyBlob(ctx, src, dst, id)
+		if err != nil {
+			return err
+		}
+		if done {
+			copied++
+		} else {
+			skipped++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("❌ Migration stopped after %d file(s): %v", copied, err)
+	}
+	log.Printf("✅ Copied %d file(s) from %s to %s, %d already present", copied, *from, *to, skipped)
+}
+
+func open(ctx context.Context, cfg storage.Config, backend string) storage.Store {
+	cfg.Backend = backend
+	store, err := storage.Open(ctx, cfg)
+	if err != nil {
+		log.Fatalf("❌ Failed to open %s storage: %v", backend, err)
+	}
+	return store
+}
+
+// copyBlob copies one file unless dst already has it and reports whether it
+// was copied.
+func copyBlob(ctx context.Context, src, dst storage.Store, id string) (bool, error) {
+	existing, err := dst.Open(ctx, id)
+	if err == nil {
+		existing.Close()
+		return false, nil
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return false, err
+	}
+
+	blob, err := src.Open(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	defer blob.Close()
+
+	// Files uploaded before content types were recorded have none; store the
+	// sniffed one so backends that always keep a type do not default to
+	// application/octet-stream.
+	if blob.Meta.ContentType == "" {
+		head := make([]byte, 512)
+		n, err := io.ReadFull(blob, head)
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+			return false, err
+		}
+		blob.Meta.ContentType = http.DetectContentType(head[:n])
+		if _, err := blob.Seek(0, io.SeekStart); err != nil {
+			return false, err
+		}
+	}
+
+	if err := dst.Put(ctx, id, blob, blob.Size, blob.Meta); err != nil {
+		return false, err
+	}
+	log.Printf("copied %s (%d bytes)", id, blob.Size)
+	return true, nil
+}
@@ -0,0 +1,45 @@
+// Command repair-ratings recomputes the review aggregates of every listing
+// (average_rating, rating_score, review_count, rating_sum, rating_1..rating_5)
+// from the reviews table. The service maintains them incrementally; run this
+// after manual data fixes, whenever they are suspected to have drifted, and
+// after changing RATING_PRIOR_MEAN or RATING_PRIOR_WEIGHT.
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/joho/godotenv"
+	_ "github.com/lib/pq"
+
+	"listing-service/internal/repository"
+)
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("Warning: no .env file found, using environment variables")
+	}
+
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL == "" {
+		log.Fatal("DATABASE_URL must be set")
+	}
+	db, err := sqlx.Connect("postgres", dbURL)
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to Postgres: %v", err)
+	}
+	defer db.Close()
+
+	prior, err := repository.ParseRatingPrior(os.Getenv("RATING_PRIOR_MEAN"), os.Getenv("RATING_PRIOR_WEIGHT"))
+	if err != nil {
+		log.Fatalf("❌ Invalid rating prior: %v", err)
+	}
+
+	n, err := repository.NewReviewRepository(db, prior).RecalcAll(context.Background())
+	if err != nil {
+		log.Fatalf("❌ Failed to repair ratings: %v", err)
+	}
+	log.Printf("✅ Repaired rating aggregates of %d listing(s)", n)
+}
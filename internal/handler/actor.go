@@ -0,0 +1,13 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+	"listing-service/internal/service"
+)
+
+// actorFrom собирает service.Actor из значений, которые JWT-middleware кладёт в контекст.
+func actorFrom(c *gin.Context) service.Actor {
+	userID, _ := c.Get("user_id")
+	id, _ := userID.(string)
+	return service.Actor{UserID: id, IsAdmin: c.GetBool("is_admin")}
+}
@@ -3,31 +3,39 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"listing-service/internal/imaging"
 	"listing-service/internal/model"
 	"listing-service/internal/repository"
+	"listing-service/internal/service"
 )
 
 // ListingHandler управляет всеми операциями над объявлениями (Listings).
 type ListingHandler struct {
-	Repo *repository.ListingRepository
+	Repo    *repository.ListingRepository
+	Service *service.ListingService
+	Photos  *service.PhotoService
 }
 
 // RegisterRoutes регистрирует все роуты для Listings.
 func (h *ListingHandler) RegisterRoutes(rg *gin.RouterGroup) {
 	rg.GET("/listings", h.GetApprovedListings)
+	rg.GET("/listings/facets", h.GetFacets)
 	rg.GET("/listings/:id", h.GetListingByID)
 
 	// Создание и обновление объявлений требуют привязки к существующим ownerId и deviceId
 	rg.POST("/listings", h.CreateListing)
 	rg.PUT("/listings/:id", h.UpdateListing)
+	rg.PUT("/listings/:id/status", h.ChangeStatus)
 
 	rg.DELETE("/listings/:id", h.DeleteListing)
 
@@ -35,17 +43,66 @@ func (h *ListingHandler) RegisterRoutes(rg *gin.RouterGroup) {
 	rg.GET("/admin/listings/pending", h.GetPending)
 	rg.PUT("/admin/listings/:id/approve", h.Approve)
 	rg.PUT("/admin/listings/:id/reject", h.Reject)
+	rg.GET("/admin/listings/:id/history", h.GetHistory)
+	rg.PUT("/admin/listings/:id/restore", h.Restore)
 }
 
-// GET /api/listings?city=...&category=...&min_price=...&max_price=...&limit=...&offset=...
+// GET /api/listings?q=...&city=...&region=...&category=...&type=...&min_price=...&max_price=...&min_rating=...&lat=...&lng=...&radius_km=...&sort=...&limit=...&cursor=...
 func (h *ListingHandler) GetApprovedListings(c *gin.Context) {
+	filters, err := parseListingFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if v := c.Query("sort"); v != "" {
+		if !repository.IsListingSort(v) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unsupported sort"})
+			return
+		}
+		if _, ok := filters["q"]; v == "relevance" && !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "sort=relevance requires q"})
+			return
+		}
+		filters["sort"] = v
+	}
+	page, err := parsePageParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	list, err := h.Repo.GetFiltered(c.Request.Context(), filters, page)
+	if err != nil {
+		writePageError(c, err)
+		return
+	}
+	for i := range list.Items {
+		if l := &list.Items[i]; l.PhotoFileID != "" {
+			l.ThumbnailURL = sizeURL(fmt.Sprintf("/api/listings/%s/photo", l.ID), imaging.Thumb)
+		}
+	}
+	c.JSON(http.StatusOK, list)
+}
+
+// parseListingFilters собирает фильтры публичной выдачи из query-строки.
+// Используется и лентой, и фасетами, чтобы счётчики совпадали с выдачей.
+func parseListingFilters(c *gin.Context) (map[string]interface{}, error) {
 	filters := map[string]interface{}{}
+	if v := strings.TrimSpace(c.Query("q")); v != "" {
+		filters["q"] = v
+	}
 	if v := c.Query("category"); v != "" {
 		filters["category"] = v
 	}
 	if v := c.Query("city"); v != "" {
 		filters["city"] = v
 	}
+	if v := c.Query("region"); v != "" {
+		filters["region"] = v
+	}
+	if v := c.Query("type"); v != "" {
+		filters["type"] = v
+	}
 	if v := c.Query("min_price"); v != "" {
 		if min, err := strconv.ParseFloat(v, 64); err == nil {
 			filters["min_price"] = min
@@ -56,21 +113,128 @@ func (h *ListingHandler) GetApprovedListings(c *gin.Context) {
 			filters["max_price"] = max
 		}
 	}
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if v := c.Query("min_rating"); v != "" {
+		min, err := strconv.ParseFloat(v, 64)
+		if err != nil || min < 0 || min > 5 {
+			return nil, errors.New("min_rating must be a number between 0 and 5")
+		}
+		filters["min_rating"] = min
+	}
+	if err := parseGeoFilters(c, filters); err != nil {
+		return nil, err
+	}
+	return filters, nil
+}
 
-	list, err := h.Repo.GetFiltered(c.Request.Context(), filters, limit, offset)
+// GET /api/listings/facets?<те же фильтры, что и у GET /api/listings>&price_buckets=1000,5000,10000
+func (h *ListingHandler) GetFacets(c *gin.Context) {
+	filters, err := parseListingFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	bounds := defaultPriceBuckets
+	if v := c.Query("price_buckets"); v != "" {
+		if bounds, err = parsePriceBuckets(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	facets, err := h.Repo.GetFacets(c.Request.Context(), filters, bounds)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
-	if list == nil {
-		list = []model.Listing{}
+	c.JSON(http.StatusOK, facets)
+}
+
+// defaultPriceBuckets — границы ценовых диапазонов фасета по умолчанию.
+var defaultPriceBuckets = []float64{1000, 5000, 10000, 50000}
+
+// parsePriceBuckets разбирает список границ через запятую; границы должны возрастать.
+func parsePriceBuckets(v string) ([]float64, error) {
+	parts := strings.Split(v, ",")
+	if len(parts) > 20 {
+		return nil, fmt.Errorf("too many price_buckets")
 	}
-	c.JSON(http.StatusOK, list)
+	bounds := make([]float64, 0, len(parts))
+	for _, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price_buckets")
+		}
+		if len(bounds) > 0 && f <= bounds[len(bounds)-1] {
+			return nil, fmt.Errorf("price_buckets must be strictly increasing")
+		}
+		bounds = append(bounds, f)
+	}
+	return bounds, nil
+}
+
+// parseGeoFilters разбирает lat/lng/radius_km и проверяет, что для sort=distance
+// задана точка. В отличие от цены, некорректные координаты не игнорируются молча,
+// а возвращаются как ошибка.
+func parseGeoFilters(c *gin.Context, filters map[string]interface{}) error {
+	latStr, lngStr := c.Query("lat"), c.Query("lng")
+	sort := c.Query("sort")
+	if latStr == "" && lngStr == "" {
+		if c.Query("radius_km") != "" || sort == "distance" {
+			return fmt.Errorf("lat and lng are required for radius_km and sort=distance")
+		}
+		return nil
+	}
+
+	lat, err := strconv.ParseFloat(latStr, 64)
+	if err != nil {
+		return fmt.Errorf("invalid lat")
+	}
+	lng, err := strconv.ParseFloat(lngStr, 64)
+	if err != nil {
+		return fmt.Errorf("invalid lng")
+	}
+	if err := validateCoordinates(&lat, &lng); err != nil {
+		return err
+	}
+	filters["lat"] = lat
+	filters["lng"] = lng
+
+	if v := c.Query("radius_km"); v != "" {
+		radius, err := strconv.ParseFloat(v, 64)
+		if err != nil || radius <= 0 {
+			return fmt.Errorf("radius_km must be a positive number")
+		}
+		filters["radius_km"] = radius
+	}
+	return nil
+}
+
+// validateCoordinates проверяет, что координаты заданы парой и лежат в допустимых диапазонах.
+func validateCoordinates(lat, lng *float64) error {
+	if (lat == nil) != (lng == nil) {
+		return fmt.Errorf("latitude and longitude must be set together")
+	}
+	if lat == nil {
+		return nil
+	}
+	if *lat < -90 || *lat > 90 {
+		return fmt.Errorf("latitude must be between -90 and 90")
+	}
+	if *lng < -180 || *lng > 180 {
+		return fmt.Errorf("longitude must be between -180 and 180")
+	}
+	return nil
+}
+
+// ModerationDTO — последнее решение модератора, видно владельцу объявления.
+type ModerationDTO struct {
+	Reason      *string `json:"reason,omitempty"`
+	Note        *string `json:"note,omitempty"`
+	ModeratedBy *string `json:"moderatedBy,omitempty"`
+	ModeratedAt string  `json:"moderatedAt"`
 }
 
-// GET /api/listings/:id
 // GET /api/listings/:id
 func (h *ListingHandler) GetListingByID(c *gin.Context) {
 	id := c.Param("id")
@@ -80,20 +244,35 @@ func (h *ListingHandler) GetListingByID(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
 		return
 	}
+	// Неопубликованные объявления видят только владелец и админы
+	actor := actorFrom(c)
+	if !actor.CanView(listing) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+		return
+	}
 
 	// Собираем ответ с photo_url
 	type ListingResponse struct {
-		ID            string  `json:"id"`
-		Title         string  `json:"title"`
-		Description   string  `json:"description"`
-		Price         float64 `json:"price"`
-		Category      string  `json:"category"`
-		City          string  `json:"city"`
-		Region        string  `json:"region"`
-		Status        string  `json:"status"`
-		Type          string  `json:"type"`
-		AverageRating float64 `json:"averageRating"`
-		PhotoURL      string  `json:"photo_url,omitempty"`
+		ID            string   `json:"id"`
+		Title         string   `json:"title"`
+		Description   string   `json:"description"`
+		Price         float64  `json:"price"`
+		Category      string   `json:"category"`
+		City          string   `json:"city"`
+		Region        string   `json:"region"`
+		Latitude      *float64 `json:"latitude,omitempty"`
+		Longitude     *float64 `json:"longitude,omitempty"`
+		Status        string   `json:"status"`
+		Type          string   `json:"type"`
+		AverageRating float64  `json:"averageRating"`
+		ReviewCount   int      `json:"reviewCount"`
+		PhotoURL      string   `json:"photo_url,omitempty"` // обложка
+
+		RatingDistribution model.RatingDistribution `json:"ratingDistribution"`
+		Photos             []ListingPhotoDTO        `json:"photos"` // галерея в порядке показа
+
+		// Только для владельца и админов
+		Moderation *ModerationDTO `json:"moderation,omitempty"`
 	}
 
 	resp := ListingResponse{
@@ -104,31 +283,53 @@ func (h *ListingHandler) GetListingByID(c *gin.Context) {
 		Category:      listing.Category,
 		City:          listing.City,
 		Region:        listing.Region,
+		Latitude:      listing.Latitude,
+		Longitude:     listing.Longitude,
 		Status:        listing.Status,
 		Type:          listing.Type,
 		AverageRating: listing.AverageRating,
+		ReviewCount:   listing.ReviewCount,
+
+		RatingDistribution: listing.RatingDistribution,
 	}
 
 	if listing.PhotoFileID != "" {
 		resp.PhotoURL = fmt.Sprintf("/api/listings/%s/photo", listing.ID)
 	}
+	photos, err := h.Photos.ListingPhotos(c.Request.Context(), listing.ID, actor)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	resp.Photos = toListingPhotos(photos)
+
+	if listing.ModeratedAt != nil && actor.CanManage(listing) {
+		resp.Moderation = &ModerationDTO{
+			Reason:      listing.ModerationReason,
+			Note:        listing.ModerationNote,
+			ModeratedBy: listing.ModeratedBy,
+			ModeratedAt: *listing.ModeratedAt,
+		}
+	}
 
 	c.JSON(http.StatusOK, resp)
 }
 
 // CreateListingRequestDTO — поля, которые клиент отправляет при создании объявления.
 type CreateListingRequestDTO struct {
-	OwnerID     string  `json:"ownerId" binding:"required"`
-	DeviceID    string  `json:"deviceId" binding:"required"`
-	Title       string  `json:"title" binding:"required"`
-	Description string  `json:"description" binding:"required"`
-	Price       float64 `json:"price" binding:"required"`
-	Category    string  `json:"category" binding:"required"`
-	City        string  `json:"city" binding:"required"`
-	Region      string  `json:"region" binding:"required"`
-	ImageURL    string  `json:"imageUrl" binding:"required"`
-	Status      string  `json:"status" binding:"required"`
-	Type        string  `json:"type" binding:"required"`
+	OwnerID     string   `json:"ownerId" binding:"required"`
+	DeviceID    string   `json:"deviceId" binding:"required"`
+	Title       string   `json:"title" binding:"required"`
+	Description string   `json:"description" binding:"required"`
+	Price       float64  `json:"price" binding:"required"`
+	Category    string   `json:"category" binding:"required"`
+	City        string   `json:"city" binding:"required"`
+	Region      string   `json:"region" binding:"required"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	ImageURL    string   `json:"imageUrl" binding:"required"`
+	Status      string   `json:"status" binding:"required"`
+	Type        string   `json:"type" binding:"required"`
 }
 
 type CreateDeviceRequest struct {
@@ -143,7 +344,8 @@ type CreateDeviceRequest struct {
 	Region      string
 }
 
-// CreateListing создаёт новое объявление, проверяя сначала, что ownerId и deviceId существуют.
+// CreateListing создаёт новое объявление от имени пользователя из токена,
+// проверяя сначала, что владелец существует, и регистрируя устройство в Device Service.
 func (h *ListingHandler) CreateListing(c *gin.Context) {
 	var req model.Listing
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -151,8 +353,29 @@ func (h *ListingHandler) CreateListing(c *gin.Context) {
 		return
 	}
 
+	// Владелец — автор токена; указать другого владельца может только админ
+	actor := actorFrom(c)
+	if req.OwnerID == "" {
+		req.OwnerID = actor.UserID
+	}
+	if !actor.CanOwn(req.OwnerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "cannot create listings for another user"})
+		return
+	}
+
 	log.Printf("[CreateListing] req.OwnerID = '%s'", req.OwnerID)
 
+	if err := validateCoordinates(req.Latitude, req.Longitude); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Статус проверяем до обращения к Device Service, чтобы не создавать лишних устройств
+	status, err := service.InitialStatus(req.Status)
+	if err != nil {
+		writeListingError(c, err)
+		return
+	}
+
 	userExists, err := h.checkUserExists(c, req.OwnerID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "error checking user"})
@@ -215,16 +438,18 @@ func (h *ListingHandler) CreateListing(c *gin.Context) {
 		Category:      req.Category,
 		City:          req.City,
 		Region:        req.Region,
+		Latitude:      req.Latitude,
+		Longitude:     req.Longitude,
 		ImageURL:      req.ImageURL,
-		Status:        req.Status,
+		Status:        status,
 		Type:          req.Type,
 		CreatedAt:     now,
 		UpdatedAt:     now,
 		AverageRating: 0.00,
 	}
 
-	if err := h.Repo.Create(c.Request.Context(), listing); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	if err := h.Service.Create(c.Request.Context(), listing, actor); err != nil {
+		writeListingError(c, err)
 		return
 	}
 
@@ -233,17 +458,19 @@ func (h *ListingHandler) CreateListing(c *gin.Context) {
 
 // UpdateListingRequestDTO — поля для обновления объявления.
 type UpdateListingRequestDTO struct {
-	OwnerID     string  `json:"ownerId" binding:"required"`
-	DeviceID    string  `json:"deviceId" binding:"required"`
-	Title       string  `json:"title" binding:"required"`
-	Description string  `json:"description" binding:"required"`
-	Price       float64 `json:"price" binding:"required"`
-	Category    string  `json:"category" binding:"required"`
-	City        string  `json:"city" binding:"required"`
-	Region      string  `json:"region" binding:"required"`
-	ImageURL    string  `json:"imageUrl" binding:"required"`
-	Status      string  `json:"status" binding:"required"`
-	Type        string  `json:"type" binding:"required"`
+	OwnerID     string   `json:"ownerId" binding:"required"`
+	DeviceID    string   `json:"deviceId" binding:"required"`
+	Title       string   `json:"title" binding:"required"`
+	Description string   `json:"description" binding:"required"`
+	Price       float64  `json:"price" binding:"required"`
+	Category    string   `json:"category" binding:"required"`
+	City        string   `json:"city" binding:"required"`
+	Region      string   `json:"region" binding:"required"`
+	Latitude    *float64 `json:"latitude"`
+	Longitude   *float64 `json:"longitude"`
+	ImageURL    string   `json:"imageUrl" binding:"required"`
+	Status      string   `json:"status"` // необязательно; смена статуса идёт по жизненному циклу
+	Type        string   `json:"type" binding:"required"`
 }
 
 // UpdateListing обновляет существующее объявление, проверяя сначала ownerId и deviceId.
@@ -254,6 +481,10 @@ func (h *ListingHandler) UpdateListing(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
 		return
 	}
+	if err := validateCoordinates(req.Latitude, req.Longitude); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
 	// 1. Проверяем пользователя в User Service
 	userExists, err := h.checkUserExists(c, req.OwnerID)
@@ -293,64 +524,147 @@ func (h *ListingHandler) UpdateListing(c *gin.Context) {
 	current.Category = req.Category
 	current.City = req.City
 	current.Region = req.Region
+	current.Latitude = req.Latitude
+	current.Longitude = req.Longitude
 	current.ImageURL = req.ImageURL
-	current.Status = req.Status
 	current.Type = req.Type
 	current.UpdatedAt = time.Now().Format(time.RFC3339)
 
-	// 5. Сохраняем
-	if err := h.Repo.Update(c.Request.Context(), current); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	// 5. Сохраняем; статус меняется только допустимым переходом
+	if err := h.Service.Update(c.Request.Context(), current, req.Status, actorFrom(c)); err != nil {
+		writeListingError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, current)
 }
 
-// DeleteListing удаляет объявление по ID.
+// DeleteListing мягко удаляет объявление по ID; админ может его восстановить
+// до окончательной очистки по сроку хранения.
 func (h *ListingHandler) DeleteListing(c *gin.Context) {
 	id := c.Param("id")
-	if err := h.Repo.Delete(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+	if err := h.Service.Delete(c.Request.Context(), id, actorFrom(c)); err != nil {
+		writeListingError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
 }
 
-// GET /api/admin/listings/pending?limit=10&offset=0
+// GET /api/admin/listings/pending?limit=10&cursor=...
 func (h *ListingHandler) GetPending(c *gin.Context) {
-	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
-	offset, _ := strconv.Atoi(c.DefaultQuery("offset", "0"))
-	list, err := h.Repo.GetPending(c.Request.Context(), limit, offset)
+	page, err := parsePageParams(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	if list == nil {
-		list = []model.Listing{}
+	list, err := h.Repo.GetPending(c.Request.Context(), page)
+	if err != nil {
+		writePageError(c, err)
+		return
 	}
 	c.JSON(http.StatusOK, list)
 }
 
+// PUT /api/listings/admin/:id/restore — отменить мягкое удаление
+func (h *ListingHandler) Restore(c *gin.Context) {
+	listing, err := h.Service.Restore(c.Request.Context(), c.Param("id"), actorFrom(c))
+	if err != nil {
+		writeListingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, listing)
+}
+
+// GET /api/listings/admin/:id/history?limit=...&cursor=...
+func (h *ListingHandler) GetHistory(c *gin.Context) {
+	page, err := parsePageParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	events, err := h.Service.History(c.Request.Context(), c.Param("id"), page)
+	if err != nil {
+		writePageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// ApproveRequestDTO — необязательное тело одобрения.
+type ApproveRequestDTO struct {
+	Note string `json:"note"`
+}
+
 // PUT /api/admin/listings/:id/approve
 func (h *ListingHandler) Approve(c *gin.Context) {
-	id := c.Param("id")
-	if err := h.Repo.Approve(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+	var req ApproveRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	if _, err := h.Service.Approve(c.Request.Context(), c.Param("id"), req.Note, actorFrom(c)); err != nil {
+		writeListingError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "approved"})
 }
 
+// RejectRequestDTO — причина отклонения (код из model.RejectReason*) и комментарий для владельца.
+type RejectRequestDTO struct {
+	Reason string `json:"reason" binding:"required"`
+	Note   string `json:"note"`
+}
+
 // PUT /api/admin/listings/:id/reject
 func (h *ListingHandler) Reject(c *gin.Context) {
-	id := c.Param("id")
-	if err := h.Repo.Reject(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+	var req RejectRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+	if _, err := h.Service.Reject(c.Request.Context(), c.Param("id"), req.Reason, req.Note, actorFrom(c)); err != nil {
+		writeListingError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "rejected"})
 }
 
+// ChangeStatusRequestDTO — целевой статус объявления.
+type ChangeStatusRequestDTO struct {
+	Status string `json:"status" binding:"required"`
+}
+
+// PUT /api/listings/:id/status — переход по жизненному циклу (отправить на модерацию,
+// снять с публикации, отметить проданным и т.д.).
+func (h *ListingHandler) ChangeStatus(c *gin.Context) {
+	var req ChangeStatusRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload"})
+		return
+	}
+	listing, err := h.Service.Transition(c.Request.Context(), c.Param("id"), req.Status, actorFrom(c))
+	if err != nil {
+		writeListingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, listing)
+}
+
+// writeListingError переводит ошибки ListingService в HTTP-статусы.
+func writeListingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrListingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+	case errors.Is(err, service.ErrInvalidTransition):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidReason):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
 // checkDeviceExists делает HTTP-запрос к Device Service, чтобы убедиться, что устройство существует.
 func (h *ListingHandler) checkDeviceExists(c *gin.Context, deviceID string) (bool, error) {
 	deviceServiceURL := "https://user-service-721348598691.europe-central2.run.app"
@@ -0,0 +1,36 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParsePriceBuckets(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    []float64
+		wantErr bool
+	}{
+		{in: "1000", want: []float64{1000}},
+		{in: "1000,5000,10000", want: []float64{1000, 5000, 10000}},
+		{in: " 0.5 , 2.5 ", want: []float64{0.5, 2.5}},
+		{in: "5000,1000", wantErr: true},
+		{in: "1000,1000", wantErr: true},
+		{in: "1000,abc", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20", want: []float64{
+			1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16, 17, 18, 19, 20,
+		}},
+		{in: "1,2,3,4,5,6,7,8,9,10,11,12,13,14,15,16,17,18,19,20,21", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePriceBuckets(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePriceBuckets(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parsePriceBuckets(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"listing-service/internal/pagination"
+)
+
+// parsePageParams читает limit, offset, cursor и include_total из query-строки.
+// Некорректные значения возвращаются как ошибка (400), а не игнорируются.
+func parsePageParams(c *gin.Context) (pagination.Params, error) {
+	var (
+		p   pagination.Params
+		err error
+	)
+	if p.Limit, err = pagination.ParseLimit(c.Query("limit")); err != nil {
+		return p, err
+	}
+	if p.Offset, err = pagination.ParseOffset(c.Query("offset")); err != nil {
+		return p, err
+	}
+	if v := c.Query("cursor"); v != "" {
+		if p.Cursor, err = pagination.DecodeCursor(v); err != nil {
+			return p, err
+		}
+	}
+	if v := c.Query("include_total"); v != "" {
+		if p.WithTotal, err = strconv.ParseBool(v); err != nil {
+			return p, err
+		}
+	}
+	return p, nil
+}
+
+// writePageError отвечает 400 на курсор, выданный для другой сортировки,
+// и 500 на остальные ошибки чтения страницы.
+func writePageError(c *gin.Context, err error) {
+	if errors.Is(err, pagination.ErrInvalidCursor) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
@@ -1,76 +1,274 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"mime"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"listing-service/internal/imaging"
+	"listing-service/internal/model"
 	"listing-service/internal/repository"
+	"listing-service/internal/service"
+	"listing-service/internal/storage"
 )
 
 type PhotoHandler struct {
 	Repo        *repository.PhotoRepository
 	ListingRepo *repository.ListingRepository
+	Service     *service.PhotoService
 }
 
 func (h *PhotoHandler) RegisterRoutes(rg *gin.RouterGroup) {
 	rg.POST("/listings/:id/photo", h.UploadPhoto)
 	rg.GET("/listings/:id/photo", h.DownloadPhoto)
+	rg.GET("/listings/:id/photos", h.ListPhotos)
+	rg.POST("/listings/:id/photos", h.AddPhoto)
+	rg.PUT("/listings/:id/photos/order", h.ReorderPhotos)
+	rg.GET("/listings/:id/photos/:photoId", h.DownloadGalleryPhoto)
+	rg.PUT("/listings/:id/photos/:photoId/cover", h.SetCover)
+	rg.DELETE("/listings/:id/photos/:photoId", h.DeletePhoto)
 }
 
+// ListingPhotoDTO — фото галереи в ответах API. Другие размеры доступны
+// по URL с ?size=thumb|medium|large.
+type ListingPhotoDTO struct {
+	ID           string `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnailUrl"`
+	Position     int    `json:"position"`
+	IsCover      bool   `json:"isCover"`
+}
+
+func toListingPhoto(p model.ListingPhoto) ListingPhotoDTO {
+	url := fmt.Sprintf("/api/listings/%s/photos/%s", p.ListingID, p.ID)
+	return ListingPhotoDTO{
+		ID:           p.ID,
+		URL:          url,
+		ThumbnailURL: sizeURL(url, imaging.Thumb),
+		Position:     p.Position,
+		IsCover:      p.IsCover,
+	}
+}
+
+func toListingPhotos(photos []model.ListingPhoto) []ListingPhotoDTO {
+	out := make([]ListingPhotoDTO, len(photos))
+	for i, p := range photos {
+		out[i] = toListingPhoto(p)
+	}
+	return out
+}
+
+// sizeURL добавляет к URL фото параметр варианта.
+func sizeURL(url string, size imaging.Size) string {
+	return url + "?size=" + string(size)
+}
+
+// parseSize читает ?size=. При ошибке ответ уже записан.
+func parseSize(c *gin.Context) (imaging.Size, bool) {
+	size, err := imaging.ParseSize(c.Query("size"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return imaging.Original, false
+	}
+	return size, true
+}
+
+// POST /api/listings/:id/photo — старый способ загрузки: фото добавляется
+// в галерею и становится обложкой, прежние фото сохраняются.
 func (h *PhotoHandler) UploadPhoto(c *gin.Context) {
+	if photo, ok := h.addPhoto(c, true); ok {
+		c.JSON(http.StatusOK, gin.H{"photo_id": photo.ID})
+	}
+}
+
+// POST /api/listings/:id/photos — добавить фото в конец галереи
+// (multipart: file, необязательный cover=true).
+func (h *PhotoHandler) AddPhoto(c *gin.Context) {
+	cover, _ := strconv.ParseBool(c.PostForm("cover"))
+	if photo, ok := h.addPhoto(c, cover); ok {
+		c.JSON(http.StatusCreated, toListingPhoto(*photo))
+	}
+}
+
+// multipartOverhead — запас сверх размера файла на границы, заголовки частей
+// и остальные поля формы.
+const multipartOverhead = 64 << 10
+
+// formFile возвращает поле file формы. Тело ограничено maxBytes с запасом на
+// multipart: FormFile разбирает запрос целиком, и без ограничения огромный файл
+// был бы прочитан (и сохранён во временный файл) до проверки размера в сервисе.
+// Слишком большой файл — ошибка service.ErrPhotoTooLarge.
+func formFile(c *gin.Context, maxBytes int64) (*multipart.FileHeader, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+multipartOverhead)
 	fileHeader, err := c.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) || (err == nil && fileHeader.Size > maxBytes) {
+		return nil, fmt.Errorf("%w (max %d bytes)", service.ErrPhotoTooLarge, maxBytes)
+	}
+	return fileHeader, err
+}
+
+// addPhoto загружает файл из поля file в галерею. При ошибке ответ уже записан.
+func (h *PhotoHandler) addPhoto(c *gin.Context, cover bool) (*model.ListingPhoto, bool) {
+	fileHeader, err := formFile(c, h.Service.MaxPhotoBytes())
+	if errors.Is(err, service.ErrPhotoTooLarge) {
+		writePhotoError(c, err)
+		return nil, false
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
-		return
+		return nil, false
 	}
 
 	file, err := fileHeader.Open()
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot open file"})
-		return
+		return nil, false
 	}
 	defer file.Close()
 
-	listingID := c.Param("id")
-	filename := fmt.Sprintf("listing_%s_%s", listingID, fileHeader.Filename)
+	photo, err := h.Service.AddListingPhoto(c.Request.Context(), c.Param("id"), actorFrom(c), file, fileHeader.Filename, cover)
+	if err != nil {
+		writePhotoError(c, err)
+		return nil, false
+	}
+	return photo, true
+}
+
+// GET /api/listings/:id/photos — галерея в порядке показа.
+func (h *PhotoHandler) ListPhotos(c *gin.Context) {
+	photos, err := h.Service.ListingPhotos(c.Request.Context(), c.Param("id"), actorFrom(c))
+	if err != nil {
+		writePhotoError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toListingPhotos(photos))
+}
 
-	photoID, err := h.Repo.UploadPhoto(file, filename)
+// ReorderPhotosRequestDTO — новый порядок галереи: все id фото объявления.
+type ReorderPhotosRequestDTO struct {
+	PhotoIDs []string `json:"photoIds" binding:"required"`
+}
+
+// PUT /api/listings/:id/photos/order
+func (h *PhotoHandler) ReorderPhotos(c *gin.Context) {
+	var req ReorderPhotosRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "photoIds is required"})
+		return
+	}
+	photos, err := h.Service.ReorderListingPhotos(c.Request.Context(), c.Param("id"), req.PhotoIDs, actorFrom(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "upload failed"})
+		writePhotoError(c, err)
 		return
 	}
-	err = h.ListingRepo.UpdatePhotoFileID(c.Request.Context(), listingID, photoID)
+	c.JSON(http.StatusOK, toListingPhotos(photos))
+}
+
+// PUT /api/listings/:id/photos/:photoId/cover
+func (h *PhotoHandler) SetCover(c *gin.Context) {
+	photos, err := h.Service.SetCover(c.Request.Context(), c.Param("id"), c.Param("photoId"), actorFrom(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update listing"})
+		writePhotoError(c, err)
 		return
 	}
-	// 💾 по желанию: обнови запись listing в Postgres (добавь поле photo_id)
-	c.JSON(http.StatusOK, gin.H{"photo_id": photoID})
+	c.JSON(http.StatusOK, toListingPhotos(photos))
 }
 
-func (h *PhotoHandler) DownloadPhoto(c *gin.Context) {
-	listingID := c.Param("id")
+// DELETE /api/listings/:id/photos/:photoId
+func (h *PhotoHandler) DeletePhoto(c *gin.Context) {
+	if err := h.Service.DeleteListingPhoto(c.Request.Context(), c.Param("id"), c.Param("photoId"), actorFrom(c)); err != nil {
+		writePhotoError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
 
-	// 1. Получаем listing из БД
-	listing, err := h.ListingRepo.GetByID(c.Request.Context(), listingID)
+// GET /api/listings/:id/photos/:photoId?size=thumb|medium|large
+func (h *PhotoHandler) DownloadGalleryPhoto(c *gin.Context) {
+	size, ok := parseSize(c)
+	if !ok {
+		return
+	}
+	f, err := h.Service.DownloadListingPhoto(c.Request.Context(), c.Param("id"), c.Param("photoId"), actorFrom(c), size)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+		writePhotoError(c, err)
 		return
 	}
+	writePhoto(c, f, cacheControl(c, photoCacheControl))
+}
 
-	if listing.PhotoFileID == "" {
-		c.JSON(http.StatusNotFound, gin.H{"error": "photo not found for this listing"})
+// GET /api/listings/:id/photo?size=thumb|medium|large — обложка объявления.
+func (h *PhotoHandler) DownloadPhoto(c *gin.Context) {
+	size, ok := parseSize(c)
+	if !ok {
 		return
 	}
-
-	// 2. Получаем фото из MongoDB GridFS
-	data, filename, err := h.Repo.DownloadPhoto(listing.PhotoFileID)
+	f, err := h.Service.DownloadCover(c.Request.Context(), c.Param("id"), actorFrom(c), size)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "download failed"})
+		writePhotoError(c, err)
 		return
 	}
+	writePhoto(c, f, cacheControl(c, coverCacheControl))
+}
+
+// Политики кэширования фото. Файл в хранилище не меняется, поэтому его id
+// служит ETag. URL фото галереи и отзыва всегда указывает на один файл;
+// URL обложки — на текущую обложку, поэтому его кэш всегда перепроверяется.
+const (
+	photoCacheControl = "public, max-age=3600"
+	coverCacheControl = "public, no-cache"
+)
 
-	c.Header("Content-Disposition", "inline; filename="+filename)
-	c.Data(http.StatusOK, "image/jpeg", data)
+// cacheControl делает ответ private, если запрос с токеном: владелец и админы
+// получают фото неопубликованных объявлений, и общий кэш не должен их сохранить.
+func cacheControl(c *gin.Context, policy string) string {
+	if actorFrom(c).UserID == "" {
+		return policy
+	}
+	return "private" + strings.TrimPrefix(policy, "public")
+}
+
+// writePhoto отдаёт фото потоком с сохранённым при загрузке типом и именем
+// файла. http.ServeContent выставляет Content-Length и Last-Modified, отвечает
+// 304 на условные запросы и обслуживает Range. Закрывает f.
+func writePhoto(c *gin.Context, f *storage.Blob, cacheControl string) {
+	defer f.Close()
+	c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{"filename": f.Meta.Filename}))
+	if f.Meta.ContentType != "" {
+		c.Header("Content-Type", f.Meta.ContentType)
+	}
+	c.Header("ETag", `"`+f.ID+`"`)
+	c.Header("Cache-Control", cacheControl)
+	http.ServeContent(c.Writer, c.Request, f.Meta.Filename, f.ModTime, f)
+}
+
+// writePhotoError переводит ошибки PhotoService в HTTP-статусы.
+func writePhotoError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrListingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+	case errors.Is(err, service.ErrPhotoNotFound),
+		// файла нет в хранилище или photo_file_id не подходит текущему бэкенду
+		errors.Is(err, storage.ErrNotFound),
+		errors.Is(err, storage.ErrInvalidID):
+		c.JSON(http.StatusNotFound, gin.H{"error": "photo not found"})
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "only the owner can change photos"})
+	case errors.Is(err, service.ErrTooManyPhotos):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPhotoTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUnsupportedPhoto):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrInvalidPhotoOrder):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "photoIds must list every photo of the listing exactly once"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
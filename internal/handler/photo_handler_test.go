@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"listing-service/internal/service"
+	"listing-service/internal/storage"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// Ошибки хранилища доходят до writePhotoError обёрнутыми сервисом и должны
+// давать 404, а не 500.
+func TestWritePhotoErrorStorage(t *testing.T) {
+	store, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errMissing := store.Open(context.Background(), storage.NewID())
+	_, errLegacy := store.Open(context.Background(), "legacy-file-id")
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{"blob missing from store", fmt.Errorf("PhotoService.DownloadCover: %w", errMissing), http.StatusNotFound},
+		{"legacy non-hex id", fmt.Errorf("PhotoService.DownloadListingPhoto: %w", errLegacy), http.StatusNotFound},
+		{"photo row missing", fmt.Errorf("PhotoService.DownloadListingPhoto: %w", service.ErrPhotoNotFound), http.StatusNotFound},
+		{"other error", fmt.Errorf("PhotoService.DownloadCover: %w", context.DeadlineExceeded), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			writePhotoError(c, tt.err)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (err %v)", w.Code, tt.want, tt.err)
+			}
+		})
+	}
+}
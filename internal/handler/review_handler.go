@@ -1,18 +1,25 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"listing-service/internal/imaging"
+	"listing-service/internal/model"
+	"listing-service/internal/pagination"
+	"listing-service/internal/repository"
 	"listing-service/internal/service"
+	"listing-service/internal/storage"
 )
 
 // ReviewRequestDTO is the JSON payload for creating a new review.
+// The author is always the token's sub, so there is no userId field.
 type ReviewRequestDTO struct {
-	// If you extract userID from JWT instead, you can omit this field
-	UserID  string `json:"userId" binding:"required"`
 	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
 	Comment string `json:"comment" binding:"required"`
 }
@@ -23,7 +30,50 @@ type ReviewResponseDTO struct {
 	UserID    string `json:"userId"`
 	Rating    int    `json:"rating"`
 	Comment   string `json:"comment"`
-	CreatedAt string `json:"createdAt"` // ISO‐8601 timestamp
+	CreatedAt string `json:"createdAt"`           // ISO‐8601 timestamp
+	UpdatedAt string `json:"updatedAt,omitempty"` // set once the author edits the review
+
+	HelpfulCount int  `json:"helpfulCount"`
+	VotedByMe    bool `json:"votedByMe"` // always false for anonymous requests
+
+	Photos []ReviewPhotoDTO `json:"photos"` // oldest first
+
+	Reply *ReviewReplyDTO `json:"reply,omitempty"` // the listing owner's public reply
+}
+
+// ReviewPhotoDTO is a photo attached to a review. Other sizes are served from
+// URL with ?size=thumb|medium|large.
+type ReviewPhotoDTO struct {
+	ID           string `json:"id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnailUrl"`
+}
+
+// ReviewReplyDTO is the listing owner's reply as returned to clients.
+type ReviewReplyDTO struct {
+	Text      string `json:"text"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt,omitempty"`
+}
+
+// ReplyRequestDTO is the JSON payload for creating or editing an owner reply.
+type ReplyRequestDTO struct {
+	Text string `json:"text" binding:"required"`
+}
+
+// ReportRequestDTO is the JSON payload for reporting a review.
+type ReportRequestDTO struct {
+	Reason string `json:"reason" binding:"required"` // one of model.ReportReason*
+	Note   string `json:"note"`
+}
+
+// ReportedReviewDTO is a review in the admin moderation queue.
+type ReportedReviewDTO struct {
+	ReviewResponseDTO
+	ListingID      string `json:"listingId"`
+	Hidden         bool   `json:"hidden"`
+	ReportCount    int    `json:"reportCount"`
+	LastReportedAt string `json:"lastReportedAt"`
 }
 
 // ReviewHandler ties HTTP requests to the ReviewService.
@@ -38,50 +88,129 @@ func NewReviewHandler(rs *service.ReviewService) *ReviewHandler {
 
 // RegisterRoutes registers:
 //
-//	GET  /api/listings/:id/reviews
-//	POST /api/listings/:id/reviews
+//	GET    /api/listings/:id/reviews
+//	POST   /api/listings/:id/reviews
+//	PUT    /api/listings/:id/reviews/:reviewId
+//	DELETE /api/listings/:id/reviews/:reviewId
+//	PUT    /api/listings/:id/reviews/:reviewId/reply
+//	DELETE /api/listings/:id/reviews/:reviewId/reply
+//	PUT    /api/listings/:id/reviews/:reviewId/helpful
+//	DELETE /api/listings/:id/reviews/:reviewId/helpful
+//	POST   /api/listings/:id/reviews/:reviewId/report
+//	POST   /api/listings/:id/reviews/:reviewId/photos
+//	GET    /api/listings/:id/reviews/:reviewId/photos/:photoId
+//	DELETE /api/listings/:id/reviews/:reviewId/photos/:photoId
 func (h *ReviewHandler) RegisterRoutes(router *gin.Engine) {
 	grp := router.Group("/api/listings/:id/reviews")
 	{
 		grp.GET("", h.GetReviews)
 		grp.POST("", h.CreateReview)
+		grp.PUT("/:reviewId", h.UpdateReview)
+		grp.DELETE("/:reviewId", h.DeleteReview)
+		grp.PUT("/:reviewId/reply", h.SetReply)
+		grp.DELETE("/:reviewId/reply", h.DeleteReply)
+		grp.PUT("/:reviewId/helpful", h.VoteHelpful)
+		grp.DELETE("/:reviewId/helpful", h.UnvoteHelpful)
+		grp.POST("/:reviewId/report", h.ReportReview)
+		grp.POST("/:reviewId/photos", h.UploadPhoto)
+		grp.GET("/:reviewId/photos/:photoId", h.DownloadPhoto)
+		grp.DELETE("/:reviewId/photos/:photoId", h.DeletePhoto)
 	}
 }
 
-// GetReviews handles GET /api/listings/:id/reviews
+// toReviewResponse converts a model.Review into the response DTO.
+func toReviewResponse(r model.Review) ReviewResponseDTO {
+	resp := ReviewResponseDTO{
+		ID:        r.ID, // string (UUID or stringified int)
+		UserID:    r.UserID,
+		Rating:    r.Rating,
+		Comment:   r.Comment,
+		CreatedAt: r.CreatedAt.Format(time.RFC3339),
+
+		HelpfulCount: r.HelpfulCount,
+		VotedByMe:    r.VotedByMe,
+
+		Photos: make([]ReviewPhotoDTO, len(r.Photos)),
+	}
+	for i, p := range r.Photos {
+		resp.Photos[i] = toReviewPhoto(r.ListingID, p)
+	}
+	if r.UpdatedAt != nil {
+		resp.UpdatedAt = r.UpdatedAt.Format(time.RFC3339)
+	}
+	if r.ReplyText != nil && r.ReplyCreatedAt != nil {
+		resp.Reply = &ReviewReplyDTO{
+			Text:      *r.ReplyText,
+			CreatedAt: r.ReplyCreatedAt.Format(time.RFC3339),
+		}
+		if r.ReplyUpdatedAt != nil {
+			resp.Reply.UpdatedAt = r.ReplyUpdatedAt.Format(time.RFC3339)
+		}
+	}
+	return resp
+}
+
+// GetReviews handles GET /api/listings/:id/reviews?sort=...&rating=4,5&limit=...&cursor=...
 func (h *ReviewHandler) GetReviews(c *gin.Context) {
 	// 1) Extract listingID from the URL as a string
 	listingID := c.Param("id")
 
-	// 2) Call the service (must accept listingID as a string)
-	reviews, err := h.reviewSvc.GetReviews(c.Request.Context(), listingID)
+	// 2) Parse sort, star filter and limit/cursor
+	filter, err := parseReviewFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// Set by OptionalJWTAuth; fills votedByMe for signed-in viewers
+	filter.Viewer = actorFrom(c).UserID
+	page, err := parsePageParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 3) Call the service (must accept listingID as a string)
+	reviews, err := h.reviewSvc.GetReviews(c.Request.Context(), listingID, actorFrom(c), filter, page)
 	if err != nil {
 		// If the service indicates the listing wasn’t found, return 404
-		if err.Error() == fmt.Sprintf("ReviewService.GetReviews: listing %s not found", listingID) {
+		if errors.Is(err, service.ErrListingNotFound) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
 			return
 		}
-		// Otherwise, return 500
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		// Otherwise, return 400 for a foreign cursor or 500
+		writePageError(c, err)
 		return
 	}
 
-	// 3) Convert []model.Review → []ReviewResponseDTO
-	out := make([]ReviewResponseDTO, 0, len(reviews))
-	for _, r := range reviews {
-		out = append(out, ReviewResponseDTO{
-			ID:        r.ID, // string (UUID or stringified int)
-			UserID:    r.UserID,
-			Rating:    r.Rating,
-			Comment:   r.Comment,
-			CreatedAt: r.CreatedAt.Format(time.RFC3339),
-		})
-	}
+	// 4) Convert the page of model.Review → ReviewResponseDTO
+	out := pagination.Map(reviews, toReviewResponse)
 
-	// 4) Return the JSON array
+	// 5) Return the page envelope
 	c.JSON(http.StatusOK, out)
 }
 
+// parseReviewFilter reads sort (newest, highest, lowest, most_helpful) and
+// rating, a comma-separated list of star values such as "4,5".
+func parseReviewFilter(c *gin.Context) (repository.ReviewFilter, error) {
+	var f repository.ReviewFilter
+	if v := c.Query("sort"); v != "" {
+		if !repository.IsReviewSort(v) {
+			return f, errors.New("unsupported sort")
+		}
+		f.Sort = v
+	}
+	if v := c.Query("rating"); v != "" {
+		for _, part := range strings.Split(v, ",") {
+			star, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil || star < 1 || star > 5 {
+				return f, errors.New("rating must be a comma-separated list of values from 1 to 5")
+			}
+			f.Ratings = append(f.Ratings, star)
+		}
+	}
+	return f, nil
+}
+
 // CreateReview handles POST /api/listings/:id/reviews
 func (h *ReviewHandler) CreateReview(c *gin.Context) {
 	// 1) Extract listingID from the URL as a string
@@ -94,42 +223,269 @@ func (h *ReviewHandler) CreateReview(c *gin.Context) {
 		return
 	}
 
-	// 3) (Optional) If your JWT middleware sets "userID" in context, override req.UserID here:
-	//    userIDIfc, exists := c.Get("userID")
-	//    if !exists {
-	//        c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-	//        return
-	//    }
-	//    req.UserID = userIDIfc.(string)
+	// 3) The author is the verified token's sub (set by JWTAuthMiddleware)
+	userID := actorFrom(c).UserID
+	if userID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
 
 	// 4) Call the service to insert a new review (service expects listingID as string)
 	newReview, err := h.reviewSvc.CreateReview(
 		c.Request.Context(),
 		listingID, // string
-		req.UserID,
+		userID,
 		req.Rating,
 		req.Comment,
 	)
 	if err != nil {
+		switch {
 		// If the service indicates the listing wasn’t found, return 404
-		if err.Error() == fmt.Sprintf("ReviewService.CreateReview: listing %s not found", listingID) {
+		case errors.Is(err, service.ErrListingNotFound):
 			c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
-			return
-		}
+		// Owners cannot review their own listing
+		case errors.Is(err, service.ErrOwnListingReview):
+			c.JSON(http.StatusForbidden, gin.H{"error": service.ErrOwnListingReview.Error()})
+		// One review per user per listing
+		case errors.Is(err, service.ErrDuplicateReview):
+			c.JSON(http.StatusConflict, gin.H{"error": service.ErrDuplicateReview.Error()})
 		// Otherwise, return 500
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
 		return
 	}
 
-	// 5) Build the response DTO
-	resp := ReviewResponseDTO{
-		ID:        newReview.ID, // string (UUID or stringified int)
-		UserID:    newReview.UserID,
-		Rating:    newReview.Rating,
-		Comment:   newReview.Comment,
-		CreatedAt: newReview.CreatedAt.Format(time.RFC3339),
+	// 5) Return 201 Created with the new review
+	c.JSON(http.StatusCreated, toReviewResponse(*newReview))
+}
+
+// UpdateReview handles PUT /api/listings/:id/reviews/:reviewId (author only)
+func (h *ReviewHandler) UpdateReview(c *gin.Context) {
+	var req ReviewRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rev, err := h.reviewSvc.UpdateReview(
+		c.Request.Context(),
+		c.Param("id"),
+		c.Param("reviewId"),
+		actorFrom(c),
+		req.Rating,
+		req.Comment,
+	)
+	if err != nil {
+		writeReviewError(c, err)
+		return
 	}
+	c.JSON(http.StatusOK, toReviewResponse(*rev))
+}
+
+// DeleteReview handles DELETE /api/listings/:id/reviews/:reviewId (author or admin)
+func (h *ReviewHandler) DeleteReview(c *gin.Context) {
+	if err := h.reviewSvc.DeleteReview(c.Request.Context(), c.Param("id"), c.Param("reviewId"), actorFrom(c)); err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
 
-	// 6) Return 201 Created with the new review
-	c.JSON(http.StatusCreated, resp)
+// SetReply handles PUT /api/listings/:id/reviews/:reviewId/reply (listing owner only)
+func (h *ReviewHandler) SetReply(c *gin.Context) {
+	var req ReplyRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	rev, err := h.reviewSvc.SetReply(c.Request.Context(), c.Param("id"), c.Param("reviewId"), actorFrom(c), req.Text)
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toReviewResponse(*rev))
+}
+
+// DeleteReply handles DELETE /api/listings/:id/reviews/:reviewId/reply (listing owner only)
+func (h *ReviewHandler) DeleteReply(c *gin.Context) {
+	if err := h.reviewSvc.DeleteReply(c.Request.Context(), c.Param("id"), c.Param("reviewId"), actorFrom(c)); err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// VoteHelpful handles PUT /api/listings/:id/reviews/:reviewId/helpful (one vote per user)
+func (h *ReviewHandler) VoteHelpful(c *gin.Context) {
+	rev, err := h.reviewSvc.VoteHelpful(c.Request.Context(), c.Param("id"), c.Param("reviewId"), actorFrom(c))
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toReviewResponse(*rev))
+}
+
+// UnvoteHelpful handles DELETE /api/listings/:id/reviews/:reviewId/helpful
+func (h *ReviewHandler) UnvoteHelpful(c *gin.Context) {
+	rev, err := h.reviewSvc.UnvoteHelpful(c.Request.Context(), c.Param("id"), c.Param("reviewId"), actorFrom(c))
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, toReviewResponse(*rev))
+}
+
+// ReportReview handles POST /api/listings/:id/reviews/:reviewId/report
+func (h *ReviewHandler) ReportReview(c *gin.Context) {
+	var req ReportRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+		return
+	}
+
+	report, err := h.reviewSvc.ReportReview(c.Request.Context(), c.Param("id"), c.Param("reviewId"), actorFrom(c), req.Reason, req.Note)
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, report)
+}
+
+// GetReported handles GET /api/listings/admin/reviews/reported (admin only):
+// reviews with open reports, most recently reported first.
+func (h *ReviewHandler) GetReported(c *gin.Context) {
+	page, err := parsePageParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	reviews, err := h.reviewSvc.ReportedReviews(c.Request.Context(), page)
+	if err != nil {
+		writePageError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, pagination.Map(reviews, func(r model.ReportedReview) ReportedReviewDTO {
+		return ReportedReviewDTO{
+			ReviewResponseDTO: toReviewResponse(r.Review),
+			ListingID:         r.ListingID,
+			Hidden:            r.HiddenAt != nil,
+			ReportCount:       r.ReportCount,
+			LastReportedAt:    r.LastReportedAt.Format(time.RFC3339),
+		}
+	}))
+}
+
+// HideReview handles PUT /api/listings/admin/reviews/:reviewId/hide (admin only)
+func (h *ReviewHandler) HideReview(c *gin.Context) {
+	if err := h.reviewSvc.HideReview(c.Request.Context(), c.Param("reviewId"), actorFrom(c)); err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "hidden"})
+}
+
+// RestoreReview handles PUT /api/listings/admin/reviews/:reviewId/restore (admin only)
+func (h *ReviewHandler) RestoreReview(c *gin.Context) {
+	if err := h.reviewSvc.RestoreReview(c.Request.Context(), c.Param("reviewId"), actorFrom(c)); err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "restored"})
+}
+
+// toReviewPhoto builds the public download URL of a review photo.
+func toReviewPhoto(listingID string, p model.ReviewPhoto) ReviewPhotoDTO {
+	url := fmt.Sprintf("/api/listings/%s/reviews/%s/photos/%s", listingID, p.ReviewID, p.ID)
+	return ReviewPhotoDTO{
+		ID:           p.ID,
+		URL:          url,
+		ThumbnailURL: sizeURL(url, imaging.Thumb),
+	}
+}
+
+// UploadPhoto handles POST /api/listings/:id/reviews/:reviewId/photos (author only, multipart "file")
+func (h *ReviewHandler) UploadPhoto(c *gin.Context) {
+	fileHeader, err := formFile(c, h.reviewSvc.MaxPhotoBytes())
+	if errors.Is(err, service.ErrPhotoTooLarge) {
+		writeReviewError(c, err)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "cannot open file"})
+		return
+	}
+	defer file.Close()
+
+	listingID := c.Param("id")
+	photo, err := h.reviewSvc.AddReviewPhoto(c.Request.Context(), listingID, c.Param("reviewId"), actorFrom(c), file, fileHeader.Filename)
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, toReviewPhoto(listingID, *photo))
+}
+
+// DownloadPhoto handles GET /api/listings/:id/reviews/:reviewId/photos/:photoId?size=thumb|medium|large
+func (h *ReviewHandler) DownloadPhoto(c *gin.Context) {
+	size, ok := parseSize(c)
+	if !ok {
+		return
+	}
+	f, err := h.reviewSvc.DownloadReviewPhoto(c.Request.Context(), c.Param("id"), c.Param("reviewId"), c.Param("photoId"), actorFrom(c), size)
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	writePhoto(c, f, cacheControl(c, photoCacheControl))
+}
+
+// DeletePhoto handles DELETE /api/listings/:id/reviews/:reviewId/photos/:photoId (author or admin)
+func (h *ReviewHandler) DeletePhoto(c *gin.Context) {
+	err := h.reviewSvc.DeleteReviewPhoto(c.Request.Context(), c.Param("id"), c.Param("reviewId"), c.Param("photoId"), actorFrom(c))
+	if err != nil {
+		writeReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
+
+// writeReviewError maps ReviewService errors on an existing review to HTTP statuses.
+func writeReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrListingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "listing not found"})
+	case errors.Is(err, service.ErrReviewNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "review not found"})
+	case errors.Is(err, service.ErrReplyNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "reply not found"})
+	case errors.Is(err, service.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrOwnReviewVote):
+		c.JSON(http.StatusForbidden, gin.H{"error": service.ErrOwnReviewVote.Error()})
+	case errors.Is(err, service.ErrInvalidReportReason):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDuplicateReport):
+		c.JSON(http.StatusConflict, gin.H{"error": service.ErrDuplicateReport.Error()})
+	case errors.Is(err, service.ErrPhotoNotFound),
+		// the row exists but its file is missing from the store or has a foreign id
+		errors.Is(err, storage.ErrNotFound),
+		errors.Is(err, storage.ErrInvalidID):
+		c.JSON(http.StatusNotFound, gin.H{"error": "photo not found"})
+	case errors.Is(err, service.ErrTooManyPhotos):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrPhotoTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrUnsupportedPhoto):
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
 }
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"listing-service/internal/storage"
+)
+
+// Review photo downloads hit the same store errors as gallery photos.
+func TestWriteReviewErrorStorage(t *testing.T) {
+	store, err := storage.NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, errMissing := store.Open(context.Background(), storage.NewID())
+	_, errLegacy := store.Open(context.Background(), "legacy-file-id")
+
+	for _, err := range []error{errMissing, errLegacy} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		writeReviewError(c, fmt.Errorf("ReviewService.DownloadReviewPhoto: %w", err))
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%v: status = %d, want %d", err, w.Code, http.StatusNotFound)
+		}
+	}
+}
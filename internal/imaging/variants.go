@@ -0,0 +1,156 @@
+// Package imaging проверяет загруженные фото и генерирует их уменьшенные варианты.
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // регистрирует декодер WebP для image.Decode
+)
+
+// Size — вариант фото; пустой Size означает оригинал.
+type Size string
+
+const (
+	Original Size = ""
+	Thumb    Size = "thumb"
+	Medium   Size = "medium"
+	Large    Size = "large"
+)
+
+// Sizes — все генерируемые варианты, от меньшего к большему.
+var Sizes = []Size{Thumb, Medium, Large}
+
+// maxSide — наибольшая сторона варианта в пикселях.
+var maxSide = map[Size]int{
+	Thumb:  200,
+	Medium: 800,
+	Large:  1600,
+}
+
+// jpegQuality — качество JPEG-вариантов.
+const jpegQuality = 85
+
+// Допустимые типы загружаемых фото.
+const (
+	TypeJPEG = "image/jpeg"
+	TypePNG  = "image/png"
+	TypeWebP = "image/webp"
+)
+
+// extensions — расширения файлов для допустимых типов.
+var extensions = map[string]string{
+	TypeJPEG: ".jpg",
+	TypePNG:  ".png",
+	TypeWebP: ".webp",
+}
+
+var (
+	// ErrUnknownSize возвращается ParseSize для неизвестного варианта.
+	ErrUnknownSize = errors.New("size must be one of thumb, medium, large")
+	// ErrUnsupportedType — файл не JPEG, PNG или WebP.
+	ErrUnsupportedType = errors.New("only JPEG, PNG and WebP images are allowed")
+	// ErrCorrupt — сигнатура допустимая, но изображение не декодируется.
+	ErrCorrupt = errors.New("image is corrupt")
+	// ErrTooManyPixels — у изображения больше пикселей, чем разрешено.
+	ErrTooManyPixels = errors.New("image has too many pixels")
+)
+
+// ParseSize разбирает значение параметра ?size=. Пустая строка — оригинал.
+func ParseSize(s string) (Size, error) {
+	switch size := Size(s); size {
+	case Original, Thumb, Medium, Large:
+		return size, nil
+	}
+	return Original, ErrUnknownSize
+}
+
+// Extension возвращает расширение файла для допустимого типа или "".
+func Extension(contentType string) string {
+	return extensions[contentType]
+}
+
+// Image — закодированное изображение с его MIME-типом.
+type Image struct {
+	Data        []byte
+	ContentType string
+}
+
+// Decode определяет тип по содержимому (а не по имени файла или заголовку
+// клиента) и полностью декодирует изображение, чтобы отсеять битые файлы.
+// Размеры читаются из заголовка до декодирования: маленький файл может описывать
+// огромную картинку, и изображения больше maxPixels не декодируются вовсе.
+func Decode(data []byte, maxPixels int) (image.Image, string, error) {
+	contentType := http.DetectContentType(data)
+	if Extension(contentType) == "" {
+		return nil, "", fmt.Errorf("%w (got %s)", ErrUnsupportedType, contentType)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	if pixels := int64(cfg.Width) * int64(cfg.Height); pixels > int64(maxPixels) {
+		return nil, "", fmt.Errorf("%w: %dx%d (max %d)", ErrTooManyPixels, cfg.Width, cfg.Height, maxPixels)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrCorrupt, err)
+	}
+	return src, contentType, nil
+}
+
+// Variants возвращает уменьшенные копии src. Варианты, которые не меньше
+// оригинала, не создаются — вместо них отдаётся оригинал. PNG остаётся PNG
+// (ради прозрачности), остальное кодируется в JPEG. В JPEG нет альфа-канала,
+// поэтому прозрачные области (например, у WebP) накладываются на белый фон:
+// иначе кодировщик отбросил бы альфу и они стали бы чёрными.
+func Variants(src image.Image, contentType string) (map[Size]Image, error) {
+	out := map[Size]Image{}
+	b := src.Bounds()
+	for _, size := range Sizes {
+		w, h := fit(b.Dx(), b.Dy(), maxSide[size])
+		if w == b.Dx() && h == b.Dy() {
+			continue
+		}
+		dst := image.NewRGBA(image.Rect(0, 0, w, h))
+		op := draw.Src
+		if contentType != TypePNG {
+			draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+			op = draw.Over
+		}
+		draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, op, nil)
+
+		var buf bytes.Buffer
+		img := Image{ContentType: TypeJPEG}
+		var err error
+		if contentType == TypePNG {
+			img.ContentType = TypePNG
+			err = png.Encode(&buf, dst)
+		} else {
+			err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: jpegQuality})
+		}
+		if err != nil {
+			return nil, fmt.Errorf("imaging.Variants: encode %s: %w", size, err)
+		}
+		img.Data = buf.Bytes()
+		out[size] = img
+	}
+	return out, nil
+}
+
+// fit вписывает w×h в квадрат side×side с сохранением пропорций, не увеличивая.
+func fit(w, h, side int) (int, int) {
+	if w <= side && h <= side {
+		return w, h
+	}
+	if w >= h {
+		return side, max(1, h*side/w)
+	}
+	return max(1, w*side/h), side
+}
@@ -0,0 +1,176 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 50))
+	pngData := encodePNG(t, img)
+	jpegData := encodeJPEG(t, img)
+
+	tests := []struct {
+		name      string
+		data      []byte
+		maxPixels int
+		wantType  string
+		wantErr   error
+	}{
+		{"png", pngData, 5000, TypePNG, nil},
+		{"jpeg", jpegData, 5000, TypeJPEG, nil},
+		{"pixel limit", pngData, 4999, "", ErrTooManyPixels},
+		{"text", []byte("definitely not an image"), 5000, "", ErrUnsupportedType},
+		{"gif", []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;"), 5000, "", ErrUnsupportedType},
+		{"empty", nil, 5000, "", ErrUnsupportedType},
+		// сигнатура PNG есть, но дальше мусор
+		{"truncated png", pngData[:20], 5000, "", ErrCorrupt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, contentType, err := Decode(tt.data, tt.maxPixels)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if contentType != tt.wantType {
+				t.Errorf("content type = %q, want %q", contentType, tt.wantType)
+			}
+			if got := src.Bounds().Size(); got != image.Pt(100, 50) {
+				t.Errorf("size = %v, want 100x50", got)
+			}
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Size
+		wantErr bool
+	}{
+		{"", Original, false},
+		{"thumb", Thumb, false},
+		{"medium", Medium, false},
+		{"large", Large, false},
+		{"huge", Original, true},
+		{"THUMB", Original, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSize(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseSize(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		w, h, side   int
+		wantW, wantH int
+	}{
+		{100, 50, 200, 100, 50}, // не увеличивается
+		{400, 200, 200, 200, 100},
+		{200, 400, 200, 100, 200},
+		{300, 300, 200, 200, 200},
+		{10000, 1, 200, 200, 1}, // сторона не схлопывается в 0
+		{1, 10000, 200, 1, 200},
+	}
+	for _, tt := range tests {
+		w, h := fit(tt.w, tt.h, tt.side)
+		if w != tt.wantW || h != tt.wantH {
+			t.Errorf("fit(%d, %d, %d) = %d×%d, want %d×%d", tt.w, tt.h, tt.side, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestVariants(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 1000, 500))
+	for _, tt := range []struct {
+		contentType, want string
+	}{
+		{TypeJPEG, TypeJPEG},
+		{TypePNG, TypePNG},
+		{TypeWebP, TypeJPEG},
+	} {
+		out, err := Variants(src, tt.contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// large (1600) не меньше оригинала и не создаётся
+		if _, ok := out[Large]; ok {
+			t.Errorf("%s: large variant generated for a 1000px image", tt.contentType)
+		}
+		wantSize := map[Size]image.Point{Thumb: {200, 100}, Medium: {800, 400}}
+		for size, want := range wantSize {
+			img, ok := out[size]
+			if !ok {
+				t.Fatalf("%s: %s variant missing", tt.contentType, size)
+			}
+			if img.ContentType != tt.want {
+				t.Errorf("%s: %s content type = %q, want %q", tt.contentType, size, img.ContentType, tt.want)
+			}
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(img.Data))
+			if err != nil {
+				t.Fatalf("%s: %s does not decode: %v", tt.contentType, size, err)
+			}
+			if got := image.Pt(cfg.Width, cfg.Height); got != want {
+				t.Errorf("%s: %s size = %v, want %v", tt.contentType, size, got, want)
+			}
+		}
+	}
+}
+
+// Прозрачный фон не должен становиться чёрным в JPEG и должен сохраняться в PNG.
+func TestVariantsAlpha(t *testing.T) {
+	src := image.NewNRGBA(image.Rect(0, 0, 1000, 1000)) // полностью прозрачный
+	for _, tt := range []struct {
+		contentType string
+		want        color.RGBA
+	}{
+		{TypeWebP, color.RGBA{255, 255, 255, 255}},
+		{TypePNG, color.RGBA{0, 0, 0, 0}},
+	} {
+		out, err := Variants(src, tt.contentType)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, _, err := image.Decode(bytes.NewReader(out[Thumb].Data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, g, b, a := got.At(100, 100).RGBA()
+		px := color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), uint8(a >> 8)}
+		if px != tt.want {
+			t.Errorf("%s: thumb pixel = %v, want %v", tt.contentType, px, tt.want)
+		}
+	}
+}
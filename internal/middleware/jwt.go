@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -10,68 +11,117 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+var errNoBearer = errors.New("No bearer token")
+
+// JWTAuthMiddleware пропускает любого пользователя с валидным токеном и кладёт
+// в контекст его user_id (sub) и признак is_admin. Права на конкретные
+// объявления проверяются в сервисах, админские роуты закрываются RequireAdmin.
 func JWTAuthMiddleware() gin.HandlerFunc {
 	secret := os.Getenv("JWT_SECRET")
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if !strings.HasPrefix(authHeader, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "No bearer token"})
+		claims, err := parseClaims(c, secret)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
-		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
 
-		token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-			// Вот тут — разрешить HS512!
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
-			}
-			// Можно проверить строго:
-			if token.Method.Alg() != "HS512" {
-				return nil, fmt.Errorf("Only HS512 is allowed")
-			}
-			return []byte(secret), nil
-		})
-		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		// Всё ок — пропускаем дальше
+		setIdentity(c, claims, hasRole(claims, "ADMIN"))
+		c.Next()
+	}
+}
+
+// RequireAdmin пропускает только админов. Ставится после JWTAuthMiddleware.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("is_admin") {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access only"})
 			return
 		}
-		claims, ok := token.Claims.(jwt.MapClaims)
-		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid claims"})
+		c.Next()
+	}
+}
+
+// OptionalJWTAuth — для публичных роутов, которые показывают владельцу и админам
+// больше данных. Без заголовка Authorization запрос проходит анонимно;
+// невалидный токен отклоняется с 401, чтобы клиент не получил молча «чужой» вид.
+func OptionalJWTAuth() gin.HandlerFunc {
+	secret := os.Getenv("JWT_SECRET")
+	return func(c *gin.Context) {
+		claims, err := parseClaims(c, secret)
+		if errors.Is(err, errNoBearer) {
+			c.Next()
 			return
 		}
-
-		var isAdmin bool
-		if rawRoles, exists := claims["roles"]; exists {
-			switch roles := rawRoles.(type) {
-			case []interface{}:
-				for _, r := range roles {
-					if s, ok := r.(string); ok && s == "ADMIN" {
-						isAdmin = true
-						break
-					}
-				}
-			case []string:
-				for _, s := range roles {
-					if s == "ADMIN" {
-						isAdmin = true
-						break
-					}
-				}
-			case string:
-				if roles == "ADMIN" {
-					isAdmin = true
-				}
-			}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
 		}
+		setIdentity(c, claims, hasRole(claims, "ADMIN"))
+		c.Next()
+	}
+}
 
-		if !isAdmin {
-			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access only"})
-			return
+// parseClaims достаёт Bearer-токен из заголовка и проверяет подпись (только HS512).
+func parseClaims(c *gin.Context, secret string) (jwt.MapClaims, error) {
+	authHeader := c.GetHeader("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return nil, errNoBearer
+	}
+	tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
+
+	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
+		// Вот тут — разрешить HS512!
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+		// Можно проверить строго:
+		if token.Method.Alg() != "HS512" {
+			return nil, fmt.Errorf("Only HS512 is allowed")
 		}
+		return []byte(secret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("Invalid token")
+	}
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("Invalid claims")
+	}
+	// Без sub нельзя проверить владельца объявления
+	if sub, ok := claims["sub"].(string); !ok || sub == "" {
+		return nil, errors.New("Invalid claims")
+	}
+	return claims, nil
+}
 
-		// Всё ок — пропускаем дальше
-		c.Set("user_id", claims["sub"])
-		c.Next()
+// hasRole проверяет claim "roles": массив строк или одна строка.
+func hasRole(claims jwt.MapClaims, role string) bool {
+	rawRoles, exists := claims["roles"]
+	if !exists {
+		return false
+	}
+	switch roles := rawRoles.(type) {
+	case []interface{}:
+		for _, r := range roles {
+			if s, ok := r.(string); ok && s == role {
+				return true
+			}
+		}
+	case []string:
+		for _, s := range roles {
+			if s == role {
+				return true
+			}
+		}
+	case string:
+		return roles == role
 	}
+	return false
+}
+
+// setIdentity кладёт в контекст данные пользователя для хендлеров.
+func setIdentity(c *gin.Context, claims jwt.MapClaims, isAdmin bool) {
+	c.Set("user_id", claims["sub"])
+	c.Set("is_admin", isAdmin)
 }
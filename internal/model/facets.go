@@ -0,0 +1,24 @@
+package model
+
+// FacetCount — число объявлений с данным значением поля.
+type FacetCount struct {
+	Value string `db:"value" json:"value"`
+	Count int    `db:"count" json:"count"`
+}
+
+// PriceBucket — число объявлений в ценовом диапазоне [Min, Max).
+// Min отсутствует у первого диапазона, Max — у последнего.
+type PriceBucket struct {
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+	Count int      `json:"count"`
+}
+
+// ListingFacets — счётчики для фильтров боковой панели.
+type ListingFacets struct {
+	Category []FacetCount  `json:"category"`
+	City     []FacetCount  `json:"city"`
+	Region   []FacetCount  `json:"region"`
+	Type     []FacetCount  `json:"type"`
+	Price    []PriceBucket `json:"price"`
+}
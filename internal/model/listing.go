@@ -1,20 +1,91 @@
 package model
 
 type Listing struct {
-	ID            string  `db:"id" json:"id"`
-	OwnerID       string  `db:"owner_id" json:"owner_id"`
-	DeviceID      string  `db:"device_id" json:"deviceId,omitempty"`
-	PhotoFileID   string  `db:"photo_file_id" json:"photoFileId"`
-	Title         string  `db:"title" json:"title"`
-	Description   string  `db:"description" json:"description"`
-	Price         float64 `db:"price" json:"price"`
-	Category      string  `db:"category" json:"category"`
-	City          string  `db:"city" json:"city"`
-	Region        string  `db:"region" json:"region"`
-	ImageURL      string  `db:"image_url" json:"image_url"`
-	Status        string  `db:"status" json:"status"` // pending/approved/rejected
-	Type          string  `db:"type" json:"type"`     // rent/sale/search
-	CreatedAt     string  `db:"created_at" json:"created_at"`
-	UpdatedAt     string  `db:"updated_at" json:"updated_at"`
-	AverageRating float64 `db:"average_rating" json:"averageRating"`
+	ID            string   `db:"id" json:"id"`
+	OwnerID       string   `db:"owner_id" json:"owner_id"`
+	DeviceID      string   `db:"device_id" json:"deviceId,omitempty"`
+	PhotoFileID   string   `db:"photo_file_id" json:"photoFileId"`
+	Title         string   `db:"title" json:"title"`
+	Description   string   `db:"description" json:"description"`
+	Price         float64  `db:"price" json:"price"`
+	Category      string   `db:"category" json:"category"`
+	City          string   `db:"city" json:"city"`
+	Region        string   `db:"region" json:"region"`
+	Latitude      *float64 `db:"latitude" json:"latitude,omitempty"`
+	Longitude     *float64 `db:"longitude" json:"longitude,omitempty"`
+	ImageURL      string   `db:"image_url" json:"image_url"`
+	Status        string   `db:"status" json:"status"` // см. Status* ниже
+	Type          string   `db:"type" json:"type"`     // rent/sale/search
+	CreatedAt     string   `db:"created_at" json:"created_at"`
+	UpdatedAt     string   `db:"updated_at" json:"updated_at"`
+	AverageRating float64  `db:"average_rating" json:"averageRating"`
+	ReviewCount   int      `db:"review_count" json:"reviewCount"`
+	// Байесовская оценка с учётом числа отзывов; по ней работает sort=rating
+	RatingScore float64 `db:"rating_score" json:"ratingScore"`
+
+	// Гистограмма оценок; поддерживается вместе с average_rating и review_count.
+	RatingDistribution `json:"ratingDistribution"`
+
+	// Результат последней модерации. В публичные ответы не попадает —
+	// владелец и админы видят его через GetListingByID.
+	ModerationReason *string `db:"moderation_reason" json:"-"`
+	ModerationNote   *string `db:"moderation_note" json:"-"`
+	ModeratedBy      *string `db:"moderated_by" json:"-"`
+	ModeratedAt      *string `db:"moderated_at" json:"-"`
+
+	// ThumbnailURL — миниатюра обложки для карточек в выдаче; заполняет хендлер
+	ThumbnailURL string `db:"-" json:"thumbnail_url,omitempty"`
+
+	// DistanceKm заполняется только в гео-поиске (расстояние до точки запроса)
+	DistanceKm *float64 `db:"distance_km" json:"distance_km,omitempty"`
+	// Rank заполняется только в полнотекстовом поиске (релевантность, ts_rank)
+	Rank *float64 `db:"rank" json:"-"`
+}
+
+// RatingDistribution — число отзывов с каждой оценкой от 1 до 5.
+type RatingDistribution struct {
+	One   int `db:"rating_1" json:"1"`
+	Two   int `db:"rating_2" json:"2"`
+	Three int `db:"rating_3" json:"3"`
+	Four  int `db:"rating_4" json:"4"`
+	Five  int `db:"rating_5" json:"5"`
+}
+
+// Статусы жизненного цикла объявления. Допустимые переходы между ними
+// описаны в service.ListingService.
+const (
+	StatusDraft    = "draft"
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+	StatusArchived = "archived"
+	StatusSold     = "sold"
+	StatusExpired  = "expired"
+)
+
+// Коды причин отклонения объявления модератором.
+const (
+	RejectReasonProhibitedItem = "prohibited_item"
+	RejectReasonMisleading     = "misleading"
+	RejectReasonPoorPhotos     = "poor_photos"
+	RejectReasonWrongCategory  = "wrong_category"
+	RejectReasonDuplicate      = "duplicate"
+	RejectReasonOther          = "other"
+)
+
+// IsRejectReason сообщает, известен ли код причины отклонения.
+func IsRejectReason(code string) bool {
+	switch code {
+	case RejectReasonProhibitedItem, RejectReasonMisleading, RejectReasonPoorPhotos,
+		RejectReasonWrongCategory, RejectReasonDuplicate, RejectReasonOther:
+		return true
+	}
+	return false
+}
+
+// Moderation — решение модератора по объявлению.
+type Moderation struct {
+	Reason      string // код причины; только для отклонения
+	Note        string // комментарий для владельца, необязателен
+	ModeratorID string // sub из JWT модератора
 }
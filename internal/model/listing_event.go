@@ -0,0 +1,30 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Действия, которые записываются в историю объявления.
+const (
+	EventCreated       = "created"
+	EventUpdated       = "updated"
+	EventDeleted       = "deleted"
+	EventRestored      = "restored"
+	EventPurged        = "purged"
+	EventStatusChanged = "status_changed"
+	EventApproved      = "approved"
+	EventRejected      = "rejected"
+)
+
+// ListingEvent — запись истории объявления. OldValue/NewValue содержат
+// изменившиеся поля (или весь снимок при создании и удалении).
+type ListingEvent struct {
+	ID        int64           `db:"id" json:"id"`
+	ListingID string          `db:"listing_id" json:"listingId"`
+	ActorID   string          `db:"actor_id" json:"actorId"`
+	Action    string          `db:"action" json:"action"`
+	OldValue  json.RawMessage `db:"old_value" json:"oldValue"`
+	NewValue  json.RawMessage `db:"new_value" json:"newValue"`
+	CreatedAt time.Time       `db:"created_at" json:"createdAt"`
+}
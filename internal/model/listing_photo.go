@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// ListingPhoto — фото из галереи объявления. Порядок задаёт Position
+// (от меньшего к большему), обложка ровно одна на объявление; её файл
+// дублируется в Listing.PhotoFileID для старого GET /api/listings/:id/photo.
+// Уменьшенные копии лежат рядом с оригиналом, см. PhotoVariants.
+type ListingPhoto struct {
+	ID        string    `db:"id" json:"id"`
+	ListingID string    `db:"listing_id" json:"listingId"`
+	FileID    string    `db:"file_id" json:"-"`
+	Position  int       `db:"position" json:"position"`
+	IsCover   bool      `db:"is_cover" json:"isCover"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+
+	PhotoVariants
+}
@@ -0,0 +1,39 @@
+package model
+
+// PhotoVariants — файлы уменьшенных копий фото. nil — вариант не создавался
+// (оригинал и так не больше или не распознан как изображение), тогда
+// вместо него отдаётся оригинал.
+type PhotoVariants struct {
+	ThumbFileID  *string `db:"thumb_file_id" json:"-"`
+	MediumFileID *string `db:"medium_file_id" json:"-"`
+	LargeFileID  *string `db:"large_file_id" json:"-"`
+}
+
+// VariantFileID возвращает файл варианта size ("thumb", "medium", "large")
+// или original, если такого варианта нет.
+func (v PhotoVariants) VariantFileID(size, original string) string {
+	var id *string
+	switch size {
+	case "thumb":
+		id = v.ThumbFileID
+	case "medium":
+		id = v.MediumFileID
+	case "large":
+		id = v.LargeFileID
+	}
+	if id == nil {
+		return original
+	}
+	return *id
+}
+
+// AllFileIDs возвращает original вместе с файлами всех созданных вариантов.
+func (v PhotoVariants) AllFileIDs(original string) []string {
+	ids := []string{original}
+	for _, id := range []*string{v.ThumbFileID, v.MediumFileID, v.LargeFileID} {
+		if id != nil {
+			ids = append(ids, *id)
+		}
+	}
+	return ids
+}
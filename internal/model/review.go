@@ -4,10 +4,38 @@ import "time"
 
 // Review represents a user’s review of a listing.
 type Review struct {
-	ID        string    `db:"id" json:"id"` // string (UUID or stringified int)
-	ListingID string    `db:"listing_id" json:"listingId"`
-	UserID    string    `db:"user_id" json:"userId"`
-	Rating    int       `db:"rating" json:"rating"`
-	Comment   string    `db:"comment" json:"comment"`
+	ID        string     `db:"id" json:"id"` // string (UUID or stringified int)
+	ListingID string     `db:"listing_id" json:"listingId"`
+	UserID    string     `db:"user_id" json:"userId"`
+	Rating    int        `db:"rating" json:"rating"`
+	Comment   string     `db:"comment" json:"comment"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt *time.Time `db:"updated_at" json:"updatedAt,omitempty"` // nil until the author edits it
+
+	HelpfulCount int  `db:"helpful_count" json:"helpfulCount"` // drives the most_helpful sort
+	VotedByMe    bool `db:"voted_by_me" json:"votedByMe"`      // only set when listing for a signed-in viewer
+
+	// The listing owner's public reply; all nil until the owner replies.
+	ReplyText      *string    `db:"reply_text" json:"replyText,omitempty"`
+	ReplyCreatedAt *time.Time `db:"reply_created_at" json:"replyCreatedAt,omitempty"`
+	ReplyUpdatedAt *time.Time `db:"reply_updated_at" json:"replyUpdatedAt,omitempty"`
+
+	// Set when an admin hides the review; hidden reviews are not listed and
+	// do not count towards the listing's rating aggregates.
+	HiddenAt *time.Time `db:"hidden_at" json:"-"`
+	HiddenBy *string    `db:"hidden_by" json:"-"`
+
+	// Photos attached by the author, oldest first; loaded separately from the row.
+	Photos []ReviewPhoto `db:"-" json:"photos,omitempty"`
+}
+
+// ReviewPhoto is an image attached to a review. FileID points into the photo
+// store; resized copies are kept alongside, see PhotoVariants.
+type ReviewPhoto struct {
+	ID        string    `db:"id" json:"id"`
+	ReviewID  string    `db:"review_id" json:"reviewId"`
+	FileID    string    `db:"file_id" json:"-"`
 	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+
+	PhotoVariants
 }
@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// Reason codes for reporting a review.
+const (
+	ReportReasonSpam      = "spam"
+	ReportReasonOffensive = "offensive"
+	ReportReasonFake      = "fake"
+	ReportReasonOffTopic  = "off_topic"
+	ReportReasonOther     = "other"
+)
+
+// IsReportReason reports whether code is a known review report reason.
+func IsReportReason(code string) bool {
+	switch code {
+	case ReportReasonSpam, ReportReasonOffensive, ReportReasonFake,
+		ReportReasonOffTopic, ReportReasonOther:
+		return true
+	}
+	return false
+}
+
+// ReviewReport is one user's abuse report on a review. A report stays open
+// until an admin hides or restores the review.
+type ReviewReport struct {
+	ID         string     `db:"id" json:"id"`
+	ReviewID   string     `db:"review_id" json:"reviewId"`
+	ReporterID string     `db:"reporter_id" json:"reporterId"`
+	Reason     string     `db:"reason" json:"reason"`
+	Note       *string    `db:"note" json:"note,omitempty"`
+	CreatedAt  time.Time  `db:"created_at" json:"createdAt"`
+	ResolvedAt *time.Time `db:"resolved_at" json:"resolvedAt,omitempty"`
+	ResolvedBy *string    `db:"resolved_by" json:"resolvedBy,omitempty"`
+}
+
+// ReportedReview is a review in the admin moderation queue together with a
+// summary of its open reports.
+type ReportedReview struct {
+	Review
+	ReportCount    int       `db:"report_count"`
+	LastReportedAt time.Time `db:"last_reported_at"`
+}
@@ -0,0 +1,120 @@
+// Package pagination содержит общие для всех списков параметры пагинации,
+// непрозрачный курсор и конверт ответа.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+const (
+	// DefaultLimit — размер страницы, если клиент не передал limit.
+	DefaultLimit = 10
+	// MaxLimit — верхняя граница limit; большие значения обрезаются до неё.
+	MaxLimit = 100
+)
+
+var (
+	ErrInvalidLimit  = errors.New("limit must be a positive integer")
+	ErrInvalidOffset = errors.New("offset must be a non-negative integer")
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// Cursor указывает на последнюю запись отданной страницы: значение ключа
+// сортировки и id (tie-breaker). Sort фиксирует, для какого списка и какой
+// сортировки курсор был выдан (например, "reviews:newest"), чтобы его нельзя
+// было применить к другому списку или сортировке с ключом другого типа.
+type Cursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    string      `json:"id"`
+}
+
+// Encode сериализует курсор в непрозрачную строку для клиента.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor разбирает строку, полученную от клиента.
+func DecodeCursor(s string) (*Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+// Params — параметры запроса страницы. Если задан Cursor, Offset игнорируется.
+type Params struct {
+	Limit     int
+	Offset    int
+	Cursor    *Cursor
+	WithTotal bool
+}
+
+// After возвращает курсор, если он был выдан для сортировки sort того же списка.
+func (p Params) After(sort string) (*Cursor, error) {
+	if p.Cursor == nil {
+		return nil, nil
+	}
+	if p.Cursor.Sort != sort {
+		return nil, fmt.Errorf("%w: cursor was issued for a different list or sort", ErrInvalidCursor)
+	}
+	return p.Cursor, nil
+}
+
+// ParseLimit проверяет limit из query-строки: пустое значение даёт DefaultLimit,
+// значения больше MaxLimit обрезаются.
+func ParseLimit(s string) (int, error) {
+	if s == "" {
+		return DefaultLimit, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, ErrInvalidLimit
+	}
+	if n > MaxLimit {
+		n = MaxLimit
+	}
+	return n, nil
+}
+
+// ParseOffset проверяет offset из query-строки.
+func ParseOffset(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, ErrInvalidOffset
+	}
+	return n, nil
+}
+
+// Page — единый конверт ответа для списков.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	Total      *int   `json:"total,omitempty"`
+}
+
+// Map преобразует элементы страницы, сохраняя курсор и total.
+func Map[T, U any](p *Page[T], f func(T) U) *Page[U] {
+	out := &Page[U]{
+		Items:      make([]U, 0, len(p.Items)),
+		NextCursor: p.NextCursor,
+		Total:      p.Total,
+	}
+	for _, item := range p.Items {
+		out.Items = append(out.Items, f(item))
+	}
+	return out
+}
@@ -0,0 +1,108 @@
+package pagination
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	tests := []Cursor{
+		{Sort: "listings:newest", Value: "2024-05-01T10:00:00Z", ID: "c0ffee"},
+		{Sort: "listings:price_asc", Value: 1500.5, ID: "42"},
+		{Sort: "reviews:highest", Value: nil, ID: "7"},
+	}
+	for _, want := range tests {
+		got, err := DecodeCursor(want.Encode())
+		if err != nil {
+			t.Fatalf("DecodeCursor(%+v): %v", want, err)
+		}
+		if *got != want {
+			t.Errorf("round trip = %+v, want %+v", *got, want)
+		}
+	}
+}
+
+func TestDecodeCursorGarbage(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"not base64!",
+		"bm90IGpzb24",              // "not json"
+		Cursor{Sort: "x"}.Encode(), // без id
+	} {
+		if _, err := DecodeCursor(s); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", s, err)
+		}
+	}
+}
+
+func TestParamsAfter(t *testing.T) {
+	c := &Cursor{Sort: "listings:newest", Value: "2024-05-01T10:00:00Z", ID: "1"}
+	tests := []struct {
+		sort    string
+		wantErr bool
+	}{
+		{"listings:newest", false},
+		{"reviews:newest", true}, // та же сортировка другого списка
+		{"listings:price_asc", true},
+	}
+	for _, tt := range tests {
+		got, err := Params{Cursor: c}.After(tt.sort)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("After(%q) error = %v, want ErrInvalidCursor", tt.sort, err)
+			}
+			continue
+		}
+		if err != nil || got != c {
+			t.Errorf("After(%q) = %v, %v; want the cursor", tt.sort, got, err)
+		}
+	}
+
+	if got, err := (Params{}).After("listings:newest"); got != nil || err != nil {
+		t.Errorf("After without cursor = %v, %v; want nil, nil", got, err)
+	}
+}
+
+func TestParseLimit(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"", DefaultLimit, false},
+		{"1", 1, false},
+		{"50", 50, false},
+		{"100", MaxLimit, false},
+		{"101", MaxLimit, false},
+		{"100000", MaxLimit, false},
+		{"0", 0, true},
+		{"-5", 0, true},
+		{"ten", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseLimit(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseLimit(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseOffset(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    int
+		wantErr bool
+	}{
+		{"", 0, false},
+		{"0", 0, false},
+		{"30", 30, false},
+		{"-1", 0, true},
+		{"x", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseOffset(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseOffset(%q) = %d, %v; want %d, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	"listing-service/internal/model"
+	"listing-service/internal/pagination"
+)
+
+type ListingEventRepository struct {
+	DB *sqlx.DB
+	tx *sqlx.Tx // задан у копий из ListingRepository.InTx
+}
+
+func NewListingEventRepository(db *sqlx.DB) *ListingEventRepository {
+	return &ListingEventRepository{DB: db}
+}
+
+// Записать событие в историю объявления (в транзакции, если репозиторий из InTx)
+func (r *ListingEventRepository) Insert(ctx context.Context, e *model.ListingEvent) error {
+	var q dbtx = r.DB
+	if r.tx != nil {
+		q = r.tx
+	}
+	err := q.QueryRowxContext(ctx, `
+		INSERT INTO listing_events (listing_id, actor_id, action, old_value, new_value)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, e.ListingID, e.ActorID, e.Action, nullJSON(e.OldValue), nullJSON(e.NewValue)).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ListingEventRepository.Insert: %w", err)
+	}
+	return nil
+}
+
+// История объявления, от новых событий к старым
+func (r *ListingEventRepository) FindByListing(ctx context.Context, listingID string, page pagination.Params) (*pagination.Page[model.ListingEvent], error) {
+	b := &sqlBuilder{}
+	// json.RawMessage не сканирует NULL, поэтому пустые значения отдаются как JSON null
+	q := pageQuery{
+		b:       b,
+		scope:   "events",
+		from:    "listing_events",
+		columns: "id, listing_id, actor_id, action, COALESCE(old_value, 'null') AS old_value, COALESCE(new_value, 'null') AS new_value, created_at",
+		where:   "listing_id = " + b.arg(listingID),
+		order:   newestFirst,
+	}
+	q.whereArgc = len(b.args)
+
+	events, err := selectPage(ctx, r.DB, q, page, func(e *model.ListingEvent) (interface{}, string) {
+		return e.CreatedAt, strconv.FormatInt(e.ID, 10)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ListingEventRepository.FindByListing: %w", err)
+	}
+	return events, nil
+}
+
+// nullJSON передаёт пустое значение как SQL NULL, а не как пустую строку.
+func nullJSON(v []byte) interface{} {
+	if len(v) == 0 {
+		return nil
+	}
+	return string(v)
+}
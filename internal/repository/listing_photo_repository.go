@@ -0,0 +1,244 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/jmoiron/sqlx"
+	"listing-service/internal/model"
+)
+
+var (
+	// ErrGalleryFull — в галерее объявления уже максимум фото.
+	ErrGalleryFull = errors.New("gallery is full")
+	// ErrPhotoOrderMismatch — новый порядок не является перестановкой текущих фото.
+	ErrPhotoOrderMismatch = errors.New("photo order must list every photo of the listing exactly once")
+)
+
+// listingPhotoColumns — колонки, которые сканируются в model.ListingPhoto.
+const listingPhotoColumns = `id, listing_id, file_id, thumb_file_id, medium_file_id, large_file_id,
+	position, is_cover, created_at`
+
+// ListingPhotoRepository хранит галереи объявлений. Все изменения идут в
+// транзакции под блокировкой строки объявления и синхронизируют
+// listings.photo_file_id с файлом обложки.
+type ListingPhotoRepository struct {
+	DB *sqlx.DB
+}
+
+func NewListingPhotoRepository(db *sqlx.DB) *ListingPhotoRepository {
+	return &ListingPhotoRepository{DB: db}
+}
+
+// FindByListing возвращает галерею объявления в порядке показа.
+func (r *ListingPhotoRepository) FindByListing(ctx context.Context, listingID string) ([]model.ListingPhoto, error) {
+	photos := []model.ListingPhoto{}
+	query := `SELECT ` + listingPhotoColumns + ` FROM listing_photos WHERE listing_id = $1 ORDER BY position, id`
+	if err := r.DB.SelectContext(ctx, &photos, query, listingID); err != nil {
+		return nil, fmt.Errorf("ListingPhotoRepository.FindByListing: %w", err)
+	}
+	return photos, nil
+}
+
+// Add добавляет фото в конец галереи, если в ней меньше max фото. Первое фото
+// (или любое при cover = true) становится обложкой.
+func (r *ListingPhotoRepository) Add(ctx context.Context, photo *model.ListingPhoto, max int, cover bool) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ListingPhotoRepository.Add: %w", err)
+	}
+	defer tx.Rollback()
+
+	photos, err := lockGallery(ctx, tx, photo.ListingID)
+	if err != nil {
+		return fmt.Errorf("ListingPhotoRepository.Add: %w", err)
+	}
+	if len(photos) >= max {
+		return fmt.Errorf("ListingPhotoRepository.Add: %w", ErrGalleryFull)
+	}
+
+	position := 0
+	if n := len(photos); n > 0 {
+		position = photos[n-1].Position + 1
+	}
+	const insertQuery = `
+		INSERT INTO listing_photos (listing_id, file_id, thumb_file_id, medium_file_id, large_file_id, position)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, position, created_at
+	`
+	err = tx.QueryRowxContext(ctx, insertQuery, photo.ListingID, photo.FileID,
+		photo.ThumbFileID, photo.MediumFileID, photo.LargeFileID, position,
+	).Scan(&photo.ID, &photo.Position, &photo.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ListingPhotoRepository.Add: %w", err)
+	}
+	if cover || len(photos) == 0 {
+		if err := setCover(ctx, tx, photo.ListingID, photo.ID); err != nil {
+			return fmt.Errorf("ListingPhotoRepository.Add: %w", err)
+		}
+		photo.IsCover = true
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ListingPhotoRepository.Add commit: %w", err)
+	}
+	return nil
+}
+
+// Delete удаляет фото из галереи и возвращает его. Если удалена обложка,
+// обложкой становится первое из оставшихся фото. Нет фото — sql.ErrNoRows.
+func (r *ListingPhotoRepository) Delete(ctx context.Context, listingID, photoID string) (*model.ListingPhoto, error) {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ListingPhotoRepository.Delete: %w", err)
+	}
+	defer tx.Rollback()
+
+	photos, err := lockGallery(ctx, tx, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("ListingPhotoRepository.Delete: %w", err)
+	}
+	var (
+		deleted *model.ListingPhoto
+		rest    []model.ListingPhoto
+	)
+	for i := range photos {
+		if photos[i].ID == photoID {
+			deleted = &photos[i]
+		} else {
+			rest = append(rest, photos[i])
+		}
+	}
+	if deleted == nil {
+		return nil, fmt.Errorf("ListingPhotoRepository.Delete: %w", sql.ErrNoRows)
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM listing_photos WHERE id = $1`, photoID); err != nil {
+		return nil, fmt.Errorf("ListingPhotoRepository.Delete: %w", err)
+	}
+	if deleted.IsCover {
+		next := ""
+		if len(rest) > 0 {
+			next = rest[0].ID
+		}
+		if err := setCover(ctx, tx, listingID, next); err != nil {
+			return nil, fmt.Errorf("ListingPhotoRepository.Delete: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("ListingPhotoRepository.Delete commit: %w", err)
+	}
+	return deleted, nil
+}
+
+// Reorder задаёт порядок галереи: photoIDs должен содержать каждое фото
+// объявления ровно один раз.
+func (r *ListingPhotoRepository) Reorder(ctx context.Context, listingID string, photoIDs []string) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ListingPhotoRepository.Reorder: %w", err)
+	}
+	defer tx.Rollback()
+
+	photos, err := lockGallery(ctx, tx, listingID)
+	if err != nil {
+		return fmt.Errorf("ListingPhotoRepository.Reorder: %w", err)
+	}
+	current := make([]string, len(photos))
+	for i, p := range photos {
+		current[i] = p.ID
+	}
+	wanted := append([]string(nil), photoIDs...)
+	sort.Strings(current)
+	sort.Strings(wanted)
+	if len(current) != len(wanted) {
+		return fmt.Errorf("ListingPhotoRepository.Reorder: %w", ErrPhotoOrderMismatch)
+	}
+	for i := range current {
+		if current[i] != wanted[i] {
+			return fmt.Errorf("ListingPhotoRepository.Reorder: %w", ErrPhotoOrderMismatch)
+		}
+	}
+
+	for position, id := range photoIDs {
+		if _, err := tx.ExecContext(ctx, `UPDATE listing_photos SET position = $1 WHERE id = $2`, position, id); err != nil {
+			return fmt.Errorf("ListingPhotoRepository.Reorder: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ListingPhotoRepository.Reorder commit: %w", err)
+	}
+	return nil
+}
+
+// SetCover делает фото обложкой объявления. Нет фото — sql.ErrNoRows.
+func (r *ListingPhotoRepository) SetCover(ctx context.Context, listingID, photoID string) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ListingPhotoRepository.SetCover: %w", err)
+	}
+	defer tx.Rollback()
+
+	photos, err := lockGallery(ctx, tx, listingID)
+	if err != nil {
+		return fmt.Errorf("ListingPhotoRepository.SetCover: %w", err)
+	}
+	found := false
+	for _, p := range photos {
+		found = found || p.ID == photoID
+	}
+	if !found {
+		return fmt.Errorf("ListingPhotoRepository.SetCover: %w", sql.ErrNoRows)
+	}
+
+	if err := setCover(ctx, tx, listingID, photoID); err != nil {
+		return fmt.Errorf("ListingPhotoRepository.SetCover: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ListingPhotoRepository.SetCover commit: %w", err)
+	}
+	return nil
+}
+
+// lockGallery блокирует строку объявления и читает его галерею в порядке показа.
+// Удалённое или несуществующее объявление — sql.ErrNoRows.
+func lockGallery(ctx context.Context, tx *sqlx.Tx, listingID string) ([]model.ListingPhoto, error) {
+	var id string
+	const lockQuery = `SELECT id FROM listings WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	if err := tx.GetContext(ctx, &id, lockQuery, listingID); err != nil {
+		return nil, err
+	}
+	var photos []model.ListingPhoto
+	query := `SELECT ` + listingPhotoColumns + ` FROM listing_photos WHERE listing_id = $1 ORDER BY position, id`
+	if err := tx.SelectContext(ctx, &photos, query, listingID); err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+// setCover переносит флаг обложки на photoID (пустой — галерея опустела)
+// и копирует её файл в listings.photo_file_id.
+func setCover(ctx context.Context, tx *sqlx.Tx, listingID, photoID string) error {
+	const unsetQuery = `UPDATE listing_photos SET is_cover = FALSE WHERE listing_id = $1 AND is_cover`
+	if _, err := tx.ExecContext(ctx, unsetQuery, listingID); err != nil {
+		return fmt.Errorf("unset cover: %w", err)
+	}
+	if photoID == "" {
+		_, err := tx.ExecContext(ctx, `UPDATE listings SET photo_file_id = '' WHERE id = $1`, listingID)
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE listing_photos SET is_cover = TRUE WHERE id = $1`, photoID); err != nil {
+		return fmt.Errorf("set cover: %w", err)
+	}
+	const syncQuery = `
+		UPDATE listings SET photo_file_id = p.file_id
+		FROM listing_photos p
+		WHERE p.id = $1 AND listings.id = $2
+	`
+	if _, err := tx.ExecContext(ctx, syncQuery, photoID, listingID); err != nil {
+		return fmt.Errorf("sync photo_file_id: %w", err)
+	}
+	return nil
+}
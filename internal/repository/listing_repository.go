@@ -2,46 +2,140 @@ package repository
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
+	"math"
+	"time"
+
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"listing-service/internal/model"
+	"listing-service/internal/pagination"
 )
 
 type ListingRepository struct {
-	DB *sqlx.DB
+	DB    *sqlx.DB
+	tx    *sqlx.Tx    // задан у копий, которые InTx передаёт в fn
+	prior RatingPrior // rating_score нового объявления — оценка без отзывов
+}
+
+func NewListingRepository(db *sqlx.DB, prior RatingPrior) *ListingRepository {
+	return &ListingRepository{DB: db, prior: prior}
+}
+
+// dbtx — методы, общие для *sqlx.DB и *sqlx.Tx.
+type dbtx interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	NamedExecContext(ctx context.Context, query string, arg interface{}) (sql.Result, error)
+	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
+	QueryRowxContext(ctx context.Context, query string, args ...interface{}) *sqlx.Row
 }
 
-func NewListingRepository(db *sqlx.DB) *ListingRepository {
-	return &ListingRepository{DB: db}
+// exec возвращает транзакцию, если репозиторий получен из InTx, иначе пул соединений.
+func (r *ListingRepository) exec() dbtx {
+	if r.tx != nil {
+		return r.tx
+	}
+	return r.DB
 }
 
-// Создать объявление
+// InTx выполняет fn с репозиториями объявлений и их истории, привязанными
+// к одной транзакции: изменение и событие о нём фиксируются вместе.
+// Транзакция фиксируется, если fn вернула nil, иначе откатывается.
+func (r *ListingRepository) InTx(
+	ctx context.Context,
+	events *ListingEventRepository,
+	fn func(listings *ListingRepository, events *ListingEventRepository) error,
+) error {
+	tx, err := r.DB.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ListingRepository.InTx: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(&ListingRepository{DB: r.DB, tx: tx, prior: r.prior}, &ListingEventRepository{DB: events.DB, tx: tx}); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ListingRepository.InTx commit: %w", err)
+	}
+	return nil
+}
+
+// listingColumns — колонки, которые сканируются в model.Listing.
+// SELECT * не используется: служебные колонки (например, search_vector)
+// не имеют поля в модели и ломают сканирование sqlx.
+const listingColumns = `id, owner_id, device_id, photo_file_id, title, description, price, category,
+	city, region, latitude, longitude, image_url, status, type, created_at, updated_at,
+	average_rating, rating_score, review_count, rating_1, rating_2, rating_3, rating_4, rating_5,
+	moderation_reason, moderation_note, moderated_by, moderated_at`
+
+// searchQuery — tsquery для поиска по search_vector: запрос пользователя
+// разбирается и русской, и английской конфигурацией.
+const searchQuery = "(websearch_to_tsquery('russian', %[1]s) || websearch_to_tsquery('english', %[1]s))"
+
+// earthRadiusKm — средний радиус Земли, используется в формуле haversine.
+const earthRadiusKm = 6371.0
+
+// distanceExpr — расстояние по haversine (в км) от объявления до точки ($lat, $lng)
+// на сфере радиусом %[3]g. Подставляется через distanceSQL.
+// least(1, ...) защищает asin от ошибок округления для диаметрально противоположных точек.
+const distanceExpr = `(%[3]g * 2 * asin(least(1, sqrt(
+	power(sin(radians(latitude - %[1]s) / 2), 2) +
+	cos(radians(%[1]s)) * cos(radians(latitude)) * power(sin(radians(longitude - %[2]s) / 2), 2)))))`
+
+// distanceSQL возвращает distanceExpr для плейсхолдеров lat и lng с радиусом
+// earthRadiusKm — тем же, что использует boundingBox.
+func distanceSQL(lat, lng string) string {
+	return fmt.Sprintf(distanceExpr, lat, lng, earthRadiusKm)
+}
+
+// boundingBox возвращает прямоугольник, гарантированно содержащий круг
+// радиусом radiusKm вокруг точки (lat, lng). Используется как грубый фильтр по индексу.
+func boundingBox(lat, lng, radiusKm float64) (minLat, maxLat, minLng, maxLng float64) {
+	dLat := radiusKm / earthRadiusKm * 180 / math.Pi
+	minLat, maxLat = math.Max(lat-dLat, -90), math.Min(lat+dLat, 90)
+
+	cosLat := math.Cos(lat * math.Pi / 180)
+	if cosLat < 1e-6 || maxLat == 90 || minLat == -90 {
+		// У полюса долгота вырождается — ограничиваем только широту
+		return minLat, maxLat, -180, 180
+	}
+	dLng := dLat / cosLat
+	if dLng >= 180 {
+		return minLat, maxLat, -180, 180
+	}
+	minLng, maxLng = lng-dLng, lng+dLng
+	if minLng < -180 || maxLng > 180 {
+		// Круг пересекает антимеридиан — не ограничиваем долготу
+		return minLat, maxLat, -180, 180
+	}
+	return minLat, maxLat, minLng, maxLng
+}
+
+// Создать объявление. rating_score задаётся явно из настроенного априорного
+// среднего: значение по умолчанию в схеме знает только априорное по умолчанию.
 func (r *ListingRepository) Create(ctx context.Context, l *model.Listing) error {
-	_, err := r.DB.NamedExecContext(ctx, `
+	l.RatingScore = r.prior.Mean
+	_, err := r.exec().NamedExecContext(ctx, `
         INSERT INTO listings 
-            (id, owner_id, device_id, title, description, price, category, city, region, image_url, status, type, created_at, updated_at)
+            (id, owner_id, device_id, title, description, price, category, city, region, latitude, longitude, image_url, status, type, rating_score, created_at, updated_at)
         VALUES 
-            (:id, :owner_id, :device_id, :title, :description, :price, :category, :city, :region, :image_url, :status, :type, :created_at, :updated_at)
+            (:id, :owner_id, :device_id, :title, :description, :price, :category, :city, :region, :latitude, :longitude, :image_url, :status, :type, :rating_score, :created_at, :updated_at)
     `, l)
 	return err
 }
 
 // Получить все approved объявления (с пагинацией)
-func (r *ListingRepository) GetAllApproved(ctx context.Context, limit, offset int) ([]model.Listing, error) {
-	var list []model.Listing
-	err := r.DB.SelectContext(ctx, &list, `
-		SELECT * FROM listings 
-		WHERE status = 'approved'
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`, limit, offset)
-	return list, err
+func (r *ListingRepository) GetAllApproved(ctx context.Context, page pagination.Params) (*pagination.Page[model.Listing], error) {
+	return r.selectByStatus(ctx, model.StatusApproved, page)
 }
 
 // Получить объявление по ID
 func (r *ListingRepository) GetByID(ctx context.Context, id string) (*model.Listing, error) {
 	var l model.Listing
-	err := r.DB.GetContext(ctx, &l, `SELECT * FROM listings WHERE id = $1`, id)
+	err := r.exec().GetContext(ctx, &l, `SELECT `+listingColumns+` FROM listings WHERE id = $1 AND deleted_at IS NULL`, id)
 	if err != nil {
 		return nil, err
 	}
@@ -49,36 +143,66 @@ func (r *ListingRepository) GetByID(ctx context.Context, id string) (*model.List
 }
 
 // Получить все pending объявления (для модерации)
-func (r *ListingRepository) GetPending(ctx context.Context, limit, offset int) ([]model.Listing, error) {
-	var list []model.Listing
-	err := r.DB.SelectContext(ctx, &list, `
-		SELECT * FROM listings 
-		WHERE status = 'pending'
-		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
-	`, limit, offset)
-	return list, err
+func (r *ListingRepository) GetPending(ctx context.Context, page pagination.Params) (*pagination.Page[model.Listing], error) {
+	return r.selectByStatus(ctx, model.StatusPending, page)
 }
 
-// Одобрить объявление
-func (r *ListingRepository) Approve(ctx context.Context, id string) error {
-	_, err := r.DB.ExecContext(ctx, `
-		UPDATE listings SET status = 'approved', updated_at = now() WHERE id = $1
-	`, id)
-	return err
+// selectByStatus — лента объявлений в статусе status, от новых к старым.
+func (r *ListingRepository) selectByStatus(ctx context.Context, status string, page pagination.Params) (*pagination.Page[model.Listing], error) {
+	b := &sqlBuilder{}
+	q := pageQuery{
+		b:       b,
+		scope:   "listings",
+		from:    "listings",
+		columns: listingColumns,
+		where:   "deleted_at IS NULL AND status = " + b.arg(status),
+		order:   newestFirst,
+	}
+	q.whereArgc = len(b.args)
+	return selectPage(ctx, r.DB, q, page, func(l *model.Listing) (interface{}, string) {
+		return l.CreatedAt, l.ID
+	})
 }
 
-// Отклонить объявление
-func (r *ListingRepository) Reject(ctx context.Context, id string) error {
-	_, err := r.DB.ExecContext(ctx, `
-		UPDATE listings SET status = 'rejected', updated_at = now() WHERE id = $1
-	`, id)
-	return err
+// UpdateStatus переводит объявление из статуса from в статус to.
+// Возвращает false, если объявления нет или его статус уже не from.
+func (r *ListingRepository) UpdateStatus(ctx context.Context, id, from, to string) (bool, error) {
+	res, err := r.exec().ExecContext(ctx, `
+		UPDATE listings SET status = $1, updated_at = now()
+		WHERE id = $2 AND status = $3 AND deleted_at IS NULL
+	`, to, id, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
 }
 
-// Обновить объявление
+// Moderate переводит объявление из статуса from в статус to (approved/rejected)
+// и сохраняет решение модератора. Пустые причина и комментарий записываются как NULL,
+// чтобы при одобрении не осталась причина прошлого отклонения.
+// Возвращает false, если объявления нет или его статус уже не from.
+func (r *ListingRepository) Moderate(ctx context.Context, id, from, to string, m model.Moderation) (bool, error) {
+	res, err := r.exec().ExecContext(ctx, `
+		UPDATE listings SET
+			status            = $1,
+			moderation_reason = NULLIF($2, ''),
+			moderation_note   = NULLIF($3, ''),
+			moderated_by      = $4,
+			moderated_at      = now(),
+			updated_at        = now()
+		WHERE id = $5 AND status = $6 AND deleted_at IS NULL
+	`, to, m.Reason, m.Note, m.ModeratorID, id, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// Обновить объявление (статус меняется только через UpdateStatus)
 func (r *ListingRepository) Update(ctx context.Context, l *model.Listing) error {
-	_, err := r.DB.NamedExecContext(ctx, `
+	_, err := r.exec().NamedExecContext(ctx, `
         UPDATE listings SET
             owner_id    = :owner_id,
             device_id   = :device_id,
@@ -88,66 +212,338 @@ func (r *ListingRepository) Update(ctx context.Context, l *model.Listing) error
             category    = :category,
             city        = :city,
             region      = :region,
+            latitude    = :latitude,
+            longitude   = :longitude,
             image_url   = :image_url,
-            status      = :status,
             type        = :type,
             updated_at  = :updated_at
-        WHERE id = :id
+        WHERE id = :id AND deleted_at IS NULL
     `, l)
 	return err
 }
 
-// Удалить объявление
+// Удалить объявление (мягко: строка остаётся до очистки по сроку хранения)
 func (r *ListingRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.DB.ExecContext(ctx, `DELETE FROM listings WHERE id = $1`, id)
+	_, err := r.exec().ExecContext(ctx, `
+		UPDATE listings SET deleted_at = now() WHERE id = $1 AND deleted_at IS NULL
+	`, id)
 	return err
 }
 
-func (r *ListingRepository) GetFiltered(ctx context.Context, filters map[string]interface{}, limit, offset int) ([]model.Listing, error) {
-	query := "SELECT * FROM listings WHERE status = 'approved'"
-	args := []interface{}{}
-	idx := 1
+// Восстановить мягко удалённое объявление.
+// Возвращает false, если объявления нет или оно не удалено.
+func (r *ListingRepository) Restore(ctx context.Context, id string) (bool, error) {
+	res, err := r.exec().ExecContext(ctx, `
+		UPDATE listings SET deleted_at = NULL, updated_at = now() WHERE id = $1 AND deleted_at IS NOT NULL
+	`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// FindPurgeable возвращает до limit объявлений, удалённых раньше before,
+// кроме объявлений из skip.
+func (r *ListingRepository) FindPurgeable(ctx context.Context, before time.Time, skip []string, limit int) ([]model.Listing, error) {
+	var list []model.Listing
+	err := r.DB.SelectContext(ctx, &list, `
+		SELECT `+listingColumns+` FROM listings
+		WHERE deleted_at IS NOT NULL AND deleted_at < $1 AND id::text <> ALL(COALESCE($2, ARRAY[]::text[]))
+		ORDER BY deleted_at
+		LIMIT $3
+	`, before, pq.Array(skip), limit)
+	return list, err
+}
 
+// Purge окончательно удаляет мягко удалённое объявление вместе с его отзывами
+// (с голосами, жалобами и строками фото) и галереей. Вызывается внутри InTx,
+// чтобы удаление было атомарным. Файлы фото удаляет вызывающий код.
+func (r *ListingRepository) Purge(ctx context.Context, id string) error {
+	q := r.exec()
+
+	const votesQuery = `
+		DELETE FROM review_votes
+		WHERE review_id IN (SELECT id::text FROM reviews WHERE listing_id = $1)
+	`
+	if _, err := q.ExecContext(ctx, votesQuery, id); err != nil {
+		return fmt.Errorf("ListingRepository.Purge review votes: %w", err)
+	}
+	const photosQuery = `
+		DELETE FROM review_photos
+		WHERE review_id IN (SELECT id::text FROM reviews WHERE listing_id = $1)
+	`
+	if _, err := q.ExecContext(ctx, photosQuery, id); err != nil {
+		return fmt.Errorf("ListingRepository.Purge review photos: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM listing_photos WHERE listing_id = $1`, id); err != nil {
+		return fmt.Errorf("ListingRepository.Purge photos: %w", err)
+	}
+	const reportsQuery = `
+		DELETE FROM review_reports
+		WHERE review_id IN (SELECT id::text FROM reviews WHERE listing_id = $1)
+	`
+	if _, err := q.ExecContext(ctx, reportsQuery, id); err != nil {
+		return fmt.Errorf("ListingRepository.Purge review reports: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM reviews WHERE listing_id = $1`, id); err != nil {
+		return fmt.Errorf("ListingRepository.Purge reviews: %w", err)
+	}
+	if _, err := q.ExecContext(ctx, `DELETE FROM listings WHERE id = $1 AND deleted_at IS NOT NULL`, id); err != nil {
+		return fmt.Errorf("ListingRepository.Purge listing: %w", err)
+	}
+	return nil
+}
+
+// PhotoFileIDs возвращает файлы галереи объявления с их вариантами (включая
+// обложку из photo_file_id) — RetentionJob удаляет их из хранилища перед Purge.
+func (r *ListingRepository) PhotoFileIDs(ctx context.Context, id string) ([]string, error) {
+	const query = `
+		SELECT f FROM listing_photos p,
+			unnest(ARRAY[p.file_id, p.thumb_file_id, p.medium_file_id, p.large_file_id]) AS f
+		WHERE p.listing_id = $1 AND f IS NOT NULL
+		UNION
+		SELECT photo_file_id FROM listings WHERE id = $1 AND COALESCE(photo_file_id, '') <> ''
+	`
+	var ids []string
+	if err := r.DB.SelectContext(ctx, &ids, query, id); err != nil {
+		return nil, fmt.Errorf("ListingRepository.PhotoFileIDs: %w", err)
+	}
+	return ids, nil
+}
+
+// ReviewPhotoFileIDs возвращает файлы фото всех отзывов объявления с вариантами —
+// RetentionJob удаляет их из хранилища перед Purge.
+func (r *ListingRepository) ReviewPhotoFileIDs(ctx context.Context, id string) ([]string, error) {
+	const query = `
+		SELECT f FROM review_photos p
+		JOIN reviews rv ON p.review_id = rv.id::text,
+			unnest(ARRAY[p.file_id, p.thumb_file_id, p.medium_file_id, p.large_file_id]) AS f
+		WHERE rv.listing_id = $1 AND f IS NOT NULL
+	`
+	var ids []string
+	if err := r.DB.SelectContext(ctx, &ids, query, id); err != nil {
+		return nil, fmt.Errorf("ListingRepository.ReviewPhotoFileIDs: %w", err)
+	}
+	return ids, nil
+}
+
+// newestFirst — сортировка по умолчанию: новые объявления первыми.
+var newestFirst = keyset{name: "newest", key: "created_at", id: "id", desc: true}
+
+// listingSort — сортировка выдачи и способ взять из строки значение ключа для курсора.
+type listingSort struct {
+	keyset
+	value func(l *model.Listing) interface{}
+}
+
+// listingSorts — белый список сортировок, не зависящих от параметров запроса.
+// Tie-breaker по id делает порядок стабильным при равных ключах.
+var listingSorts = map[string]listingSort{
+	"newest": {newestFirst,
+		func(l *model.Listing) interface{} { return l.CreatedAt }},
+	"price_asc": {keyset{name: "price_asc", key: "price", id: "id"},
+		func(l *model.Listing) interface{} { return l.Price }},
+	"price_desc": {keyset{name: "price_desc", key: "price", id: "id", desc: true},
+		func(l *model.Listing) interface{} { return l.Price }},
+	// Не average_rating: rating_score не даёт одному отзыву на 5 звёзд
+	// обогнать сотни отзывов со средней 4.8.
+	"rating": {keyset{name: "rating", key: "rating_score", id: "id", desc: true},
+		func(l *model.Listing) interface{} { return l.RatingScore }},
+	"reviews": {keyset{name: "reviews", key: "review_count", id: "id", desc: true},
+		func(l *model.Listing) interface{} { return l.ReviewCount }},
+}
+
+// IsListingSort сообщает, поддерживается ли сортировка name.
+// relevance требует q, distance — lat/lng; это проверяет вызывающий код.
+func IsListingSort(name string) bool {
+	_, ok := listingSorts[name]
+	return ok || name == "relevance" || name == "distance"
+}
+
+// GetFiltered — публичная выдача approved-объявлений по фильтрам с keyset-пагинацией.
+func (r *ListingRepository) GetFiltered(ctx context.Context, filters map[string]interface{}, page pagination.Params) (*pagination.Page[model.Listing], error) {
+	b := &sqlBuilder{}
+	q := pageQuery{b: b, scope: "listings", from: "listings", where: listingWhere(b, filters)}
+	q.whereArgc = len(b.args)
+
+	var order listingSort
+	q.columns, order = listingOrder(b, filters)
+	q.order = order.keyset
+
+	return selectPage(ctx, r.DB, q, page, func(l *model.Listing) (interface{}, string) {
+		return order.value(l), l.ID
+	})
+}
+
+// listingWhere строит условие WHERE публичной выдачи по фильтрам запроса.
+func listingWhere(b *sqlBuilder, filters map[string]interface{}) string {
+	where := "deleted_at IS NULL AND status = 'approved'"
+
+	if v, ok := filters["q"]; ok {
+		where += " AND search_vector @@ " + fmt.Sprintf(searchQuery, b.arg(v))
+	}
 	if v, ok := filters["category"]; ok {
-		query += fmt.Sprintf(" AND category = $%d", idx)
-		args = append(args, v)
-		idx++
+		where += " AND category = " + b.arg(v)
 	}
 	if v, ok := filters["city"]; ok {
-		query += fmt.Sprintf(" AND city = $%d", idx)
-		args = append(args, v)
-		idx++
+		where += " AND city = " + b.arg(v)
+	}
+	if v, ok := filters["region"]; ok {
+		where += " AND region = " + b.arg(v)
+	}
+	if v, ok := filters["type"]; ok {
+		where += " AND type = " + b.arg(v)
 	}
 	if v, ok := filters["min_price"]; ok {
-		query += fmt.Sprintf(" AND price >= $%d", idx)
-		args = append(args, v)
-		idx++
+		where += " AND price >= " + b.arg(v)
 	}
 	if v, ok := filters["max_price"]; ok {
-		query += fmt.Sprintf(" AND price <= $%d", idx)
-		args = append(args, v)
-		idx++
+		where += " AND price <= " + b.arg(v)
+	}
+	if v, ok := filters["min_rating"]; ok {
+		where += " AND average_rating >= " + b.arg(v)
+	}
+
+	// Гео-поиск: если задан радиус, отсекаем всё, что дальше. Bounding box
+	// позволяет использовать индекс по (latitude, longitude) до расчёта haversine.
+	if lat, ok := filters["lat"].(float64); ok {
+		lng, _ := filters["lng"].(float64)
+		where += " AND latitude IS NOT NULL AND longitude IS NOT NULL"
+
+		if radius, ok := filters["radius_km"].(float64); ok {
+			minLat, maxLat, minLng, maxLng := boundingBox(lat, lng, radius)
+			where += fmt.Sprintf(" AND latitude BETWEEN %s AND %s AND longitude BETWEEN %s AND %s",
+				b.arg(minLat), b.arg(maxLat), b.arg(minLng), b.arg(maxLng))
+			where += fmt.Sprintf(" AND %s <= %s", distanceSQL(b.arg(lat), b.arg(lng)), b.arg(radius))
+		}
+	}
+	return where
+}
+
+// listingOrder выбирает сортировку выдачи и добавляет к колонкам вычисляемые
+// значения (rank, distance_km). Без явного sort выдача с q ранжируется по
+// релевантности, остальная — от новых к старым.
+func listingOrder(b *sqlBuilder, filters map[string]interface{}) (string, listingSort) {
+	columns := listingColumns
+	sort, _ := filters["sort"].(string)
+	order, ok := listingSorts[sort]
+	if !ok {
+		order = listingSorts["newest"]
+	}
+
+	if v, ok := filters["q"]; ok {
+		rank := fmt.Sprintf("ts_rank(search_vector, %s)::float8", fmt.Sprintf(searchQuery, b.arg(v)))
+		columns += ", " + rank + " AS rank"
+		if sort == "" || sort == "relevance" {
+			order = listingSort{
+				keyset{name: "relevance", key: rank, id: "id", desc: true},
+				func(l *model.Listing) interface{} { return l.Rank },
+			}
+		}
+	}
+
+	if lat, ok := filters["lat"].(float64); ok {
+		lng, _ := filters["lng"].(float64)
+		dist := distanceSQL(b.arg(lat), b.arg(lng))
+		columns += ", " + dist + " AS distance_km"
+		if sort == "distance" {
+			order = listingSort{
+				keyset{name: "distance", key: dist, id: "id"},
+				func(l *model.Listing) interface{} { return l.DistanceKm },
+			}
+		}
+	}
+	return columns, order
+}
+
+// GetFacets считает объявления публичной выдачи по категориям, городам, регионам,
+// типам и ценовым диапазонам с границами priceBounds. Каждый фасет считается без
+// собственного фильтра (например, категории — без фильтра category), чтобы
+// в панели оставались видны альтернативы уже выбранному значению.
+func (r *ListingRepository) GetFacets(ctx context.Context, filters map[string]interface{}, priceBounds []float64) (*model.ListingFacets, error) {
+	facets := &model.ListingFacets{}
+	for _, f := range []struct {
+		column string
+		dest   *[]model.FacetCount
+	}{
+		{"category", &facets.Category},
+		{"city", &facets.City},
+		{"region", &facets.Region},
+		{"type", &facets.Type},
+	} {
+		b := &sqlBuilder{}
+		query := fmt.Sprintf(`
+			SELECT %[1]s AS value, COUNT(*) AS count
+			FROM listings
+			WHERE %[2]s AND %[1]s IS NOT NULL AND %[1]s <> ''
+			GROUP BY %[1]s
+			ORDER BY count DESC, value`, f.column, listingWhere(b, without(filters, f.column)))
+		*f.dest = []model.FacetCount{}
+		if err := r.DB.SelectContext(ctx, f.dest, query, b.args...); err != nil {
+			return nil, fmt.Errorf("ListingRepository.GetFacets %s: %w", f.column, err)
+		}
+	}
+
+	price, err := r.priceFacet(ctx, without(filters, "min_price", "max_price"), priceBounds)
+	if err != nil {
+		return nil, fmt.Errorf("ListingRepository.GetFacets price: %w", err)
 	}
+	facets.Price = price
+	return facets, nil
+}
+
+// priceFacet раскладывает объявления по диапазонам цены. width_bucket возвращает
+// 0 для цен ниже первой границы и len(bounds) для цен не ниже последней.
+func (r *ListingRepository) priceFacet(ctx context.Context, filters map[string]interface{}, bounds []float64) ([]model.PriceBucket, error) {
+	b := &sqlBuilder{}
+	where := listingWhere(b, filters)
+	query := fmt.Sprintf(`
+		SELECT width_bucket(price::float8, %s::float8[]) AS bucket, COUNT(*) AS count
+		FROM listings
+		WHERE %s AND price IS NOT NULL
+		GROUP BY bucket`, b.arg(pq.Array(bounds)), where)
 
-	query += fmt.Sprintf(" ORDER BY created_at DESC LIMIT $%d OFFSET $%d", idx, idx+1)
-	args = append(args, limit, offset)
+	var rows []struct {
+		Bucket int `db:"bucket"`
+		Count  int `db:"count"`
+	}
+	if err := r.DB.SelectContext(ctx, &rows, query, b.args...); err != nil {
+		return nil, err
+	}
+
+	buckets := make([]model.PriceBucket, len(bounds)+1)
+	for i := range buckets {
+		if i > 0 {
+			buckets[i].Min = &bounds[i-1]
+		}
+		if i < len(bounds) {
+			buckets[i].Max = &bounds[i]
+		}
+	}
+	for _, row := range rows {
+		buckets[row.Bucket].Count = row.Count
+	}
+	return buckets, nil
+}
 
-	var listings []model.Listing
-	err := r.DB.SelectContext(ctx, &listings, query, args...)
-	return listings, err
+// without возвращает копию фильтров без указанных ключей.
+func without(filters map[string]interface{}, keys ...string) map[string]interface{} {
+	out := make(map[string]interface{}, len(filters))
+	for k, v := range filters {
+		out[k] = v
+	}
+	for _, k := range keys {
+		delete(out, k)
+	}
+	return out
 }
 
 func (r *ListingRepository) Exists(ctx context.Context, listingID string) (bool, error) {
 	var count int
-	const q = `SELECT COUNT(1) FROM listings WHERE id = $1`
+	const q = `SELECT COUNT(1) FROM listings WHERE id = $1 AND deleted_at IS NULL`
 	if err := r.DB.GetContext(ctx, &count, q, listingID); err != nil {
 		return false, fmt.Errorf("ListingRepository.Exists: %w", err)
 	}
 	return count > 0, nil
 }
-
-func (r *ListingRepository) UpdatePhotoFileID(ctx context.Context, listingID string, fileID string) error {
-	query := `UPDATE listings SET photo_file_id = $1 WHERE id = $2`
-	_, err := r.DB.ExecContext(ctx, query, fileID, listingID)
-	return err
-}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+// haversineKm — та же формула, что distanceExpr, для проверки boundingBox.
+func haversineKm(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180
+	dLat, dLng := (lat2-lat1)*rad, (lng2-lng1)*rad
+	h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Pow(math.Sin(dLng/2), 2)
+	return earthRadiusKm * 2 * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func TestBoundingBox(t *testing.T) {
+	tests := []struct {
+		name             string
+		lat, lng, radius float64
+		fullLng          bool // долгота не ограничивается
+	}{
+		{name: "Moscow 10 km", lat: 55.75, lng: 37.62, radius: 10},
+		{name: "equator 100 km", lat: 0, lng: 0, radius: 100},
+		{name: "near north pole", lat: 89.99, lng: 10, radius: 50, fullLng: true},
+		{name: "crosses antimeridian", lat: 60, lng: 179.9, radius: 100, fullLng: true},
+		{name: "huge radius", lat: 45, lng: 0, radius: 10000, fullLng: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			minLat, maxLat, minLng, maxLng := boundingBox(tt.lat, tt.lng, tt.radius)
+			if minLat < -90 || maxLat > 90 || minLat > tt.lat || maxLat < tt.lat {
+				t.Fatalf("latitude range [%v, %v] is invalid for %v", minLat, maxLat, tt.lat)
+			}
+			if tt.fullLng != (minLng == -180 && maxLng == 180) {
+				t.Fatalf("longitude range = [%v, %v], full range expected: %v", minLng, maxLng, tt.fullLng)
+			}
+
+			// Точки на окружности радиуса radius должны попасть в прямоугольник.
+			for bearing := 0.0; bearing < 360; bearing += 5 {
+				lat, lng := destination(tt.lat, tt.lng, bearing, tt.radius*0.999)
+				if d := haversineKm(tt.lat, tt.lng, lat, lng); math.Abs(d-tt.radius*0.999) > 0.01 {
+					t.Fatalf("destination is %v km away, want %v", d, tt.radius*0.999)
+				}
+				if lat < minLat || lat > maxLat {
+					t.Errorf("bearing %v: lat %v outside [%v, %v]", bearing, lat, minLat, maxLat)
+				}
+				if !tt.fullLng && (lng < minLng || lng > maxLng) {
+					t.Errorf("bearing %v: lng %v outside [%v, %v]", bearing, lng, minLng, maxLng)
+				}
+			}
+		})
+	}
+}
+
+// destination — точка на расстоянии distKm от (lat, lng) по азимуту bearing.
+func destination(lat, lng, bearing, distKm float64) (float64, float64) {
+	rad := math.Pi / 180
+	d := distKm / earthRadiusKm
+	lat1, lng1, brg := lat*rad, lng*rad, bearing*rad
+	lat2 := math.Asin(math.Sin(lat1)*math.Cos(d) + math.Cos(lat1)*math.Sin(d)*math.Cos(brg))
+	lng2 := lng1 + math.Atan2(math.Sin(brg)*math.Sin(d)*math.Cos(lat1), math.Cos(d)-math.Sin(lat1)*math.Sin(lat2))
+	return lat2 / rad, math.Mod(lng2/rad+540, 360) - 180
+}
+
+func TestDistanceSQLUsesEarthRadius(t *testing.T) {
+	got := distanceSQL("$1", "$2")
+	if !strings.HasPrefix(got, "(6371 * 2 * asin(") {
+		t.Errorf("distanceSQL does not start with the earth radius: %s", got)
+	}
+	if strings.Contains(got, "%!") {
+		t.Errorf("distanceSQL has a formatting error: %s", got)
+	}
+	if strings.Count(got, "$1") != 2 || strings.Count(got, "$2") != 1 {
+		t.Errorf("distanceSQL placeholders are wrong: %s", got)
+	}
+}
@@ -1,64 +1,38 @@
 package repository
 
 import (
-	_ "fmt"
+	"context"
 	"io"
-	"mime/multipart"
 
-	"go.mongodb.org/mongo-driver/bson/primitive"
-	"go.mongodb.org/mongo-driver/mongo"
-	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"listing-service/internal/storage"
 )
 
+// PhotoRepository хранит файлы фото в хранилище, выбранном конфигурацией
+// (GridFS, локальная папка или S3). id файлов выдаёт storage.NewID.
 type PhotoRepository struct {
-	DB *mongo.Database
+	Store storage.Store
 }
 
-func NewPhotoRepository(client *mongo.Client, dbName string) *PhotoRepository {
-	return &PhotoRepository{DB: client.Database(dbName)}
+func NewPhotoRepository(store storage.Store) *PhotoRepository {
+	return &PhotoRepository{Store: store}
 }
 
-func (r *PhotoRepository) UploadPhoto(file multipart.File, filename string) (string, error) {
-
-	bucket, err := gridfs.NewBucket(r.DB)
-	if err != nil {
-		return "", err
-	}
-
-	stream, err := bucket.OpenUploadStream(filename)
-	if err != nil {
+// UploadPhoto сохраняет size байт из file и возвращает id нового файла.
+func (r *PhotoRepository) UploadPhoto(ctx context.Context, file io.Reader, size int64, meta storage.Meta) (string, error) {
+	id := storage.NewID()
+	if err := r.Store.Put(ctx, id, file, size, meta); err != nil {
 		return "", err
 	}
-	defer stream.Close()
-
-	if _, err := io.Copy(stream, file); err != nil {
-		return "", err
-	}
-
-	return stream.FileID.(primitive.ObjectID).Hex(), nil
+	return id, nil
 }
-func (r *PhotoRepository) DownloadPhoto(photoID string) ([]byte, string, error) {
-	bucket, err := gridfs.NewBucket(r.DB)
-	if err != nil {
-		return nil, "", err
-	}
-
-	objID, err := primitive.ObjectIDFromHex(photoID)
-	if err != nil {
-		return nil, "", err
-	}
 
-	stream, err := bucket.OpenDownloadStream(objID)
-	if err != nil {
-		return nil, "", err
-	}
-	defer stream.Close()
-
-	data, err := io.ReadAll(stream)
-	if err != nil {
-		return nil, "", err
-	}
+// OpenPhoto открывает файл на потоковое чтение, не загружая его в память.
+// Файл нужно закрыть.
+func (r *PhotoRepository) OpenPhoto(ctx context.Context, photoID string) (*storage.Blob, error) {
+	return r.Store.Open(ctx, photoID)
+}
 
-	// Просто возвращаем фиксированное имя файла
-	return data, "photo.jpg", nil
+// DeletePhoto удаляет файл. Отсутствующий файл ошибкой не считается.
+func (r *PhotoRepository) DeletePhoto(ctx context.Context, photoID string) error {
+	return r.Store.Delete(ctx, photoID)
 }
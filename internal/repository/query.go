@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"listing-service/internal/pagination"
+)
+
+// sqlBuilder собирает позиционные параметры ($1, $2, ...) для динамических запросов.
+type sqlBuilder struct {
+	args []interface{}
+}
+
+// arg добавляет значение в список параметров и возвращает его плейсхолдер.
+func (b *sqlBuilder) arg(v interface{}) string {
+	b.args = append(b.args, v)
+	return fmt.Sprintf("$%d", len(b.args))
+}
+
+// keyset описывает сортировку для keyset-пагинации. Tie-breaker (обычно id)
+// идёт в том же направлении, что и ключ, поэтому позицию курсора можно
+// выразить одним сравнением кортежей.
+type keyset struct {
+	name string // имя сортировки, сохраняется в курсоре
+	key  string // SQL-выражение ключа сортировки
+	id   string // SQL-выражение tie-breaker
+	desc bool
+}
+
+func (k keyset) orderBy() string {
+	dir := "ASC"
+	if k.desc {
+		dir = "DESC"
+	}
+	return fmt.Sprintf("%s %s, %s %s", k.key, dir, k.id, dir)
+}
+
+// after возвращает условие «строго после курсора» в порядке сортировки.
+func (k keyset) after(b *sqlBuilder, c *pagination.Cursor) string {
+	op := ">"
+	if k.desc {
+		op = "<"
+	}
+	return fmt.Sprintf("(%s, %s) %s (%s, %s)", k.key, k.id, op, b.arg(c.Value), b.arg(c.ID))
+}
+
+// pageQuery — части запроса страницы. whereArgc — число параметров builder'а,
+// на которые ссылается where: для COUNT(*) нужны только они, а параметры
+// вычисляемых колонок (rank, distance_km) в него не передаются.
+// scope — имя списка в курсоре: одна и та же сортировка (например, newest)
+// есть у нескольких списков, и курсор одного не должен приниматься другим.
+type pageQuery struct {
+	b         *sqlBuilder
+	scope     string
+	from      string
+	columns   string
+	where     string
+	whereArgc int
+	order     keyset
+}
+
+// cursorSort — значение Cursor.Sort для страниц этого запроса.
+func (q pageQuery) cursorSort() string {
+	return q.scope + ":" + q.order.name
+}
+
+// selectPage читает одну страницу: при необходимости считает total, применяет
+// курсор (или offset) и выбирает limit+1 строк, чтобы понять, есть ли продолжение.
+// cursorOf возвращает значение ключа сортировки и id для строки.
+func selectPage[T any](
+	ctx context.Context,
+	db *sqlx.DB,
+	q pageQuery,
+	page pagination.Params,
+	cursorOf func(*T) (interface{}, string),
+) (*pagination.Page[T], error) {
+	out := &pagination.Page[T]{}
+
+	if page.WithTotal {
+		var total int
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", q.from, q.where)
+		if err := db.GetContext(ctx, &total, countQuery, q.b.args[:q.whereArgc]...); err != nil {
+			return nil, fmt.Errorf("count: %w", err)
+		}
+		out.Total = &total
+	}
+
+	after, err := page.After(q.cursorSort())
+	if err != nil {
+		return nil, err
+	}
+	where := q.where
+	offset := page.Offset
+	if after != nil {
+		where += " AND " + q.order.after(q.b, after)
+		offset = 0
+	}
+
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT %s OFFSET %s",
+		q.columns, q.from, where, q.order.orderBy(), q.b.arg(page.Limit+1), q.b.arg(offset))
+
+	var items []T
+	if err := db.SelectContext(ctx, &items, query, q.b.args...); err != nil {
+		return nil, err
+	}
+
+	if len(items) > page.Limit {
+		items = items[:page.Limit]
+		value, id := cursorOf(&items[len(items)-1])
+		out.NextCursor = pagination.Cursor{Sort: q.cursorSort(), Value: value, ID: id}.Encode()
+	}
+	if items == nil {
+		items = []T{}
+	}
+	out.Items = items
+	return out, nil
+}
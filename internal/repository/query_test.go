@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"testing"
+
+	"listing-service/internal/pagination"
+)
+
+func TestSQLBuilderArg(t *testing.T) {
+	b := &sqlBuilder{}
+	for i, want := range []string{"$1", "$2", "$3"} {
+		if got := b.arg(i); got != want {
+			t.Errorf("arg #%d = %s, want %s", i, got, want)
+		}
+	}
+	if len(b.args) != 3 {
+		t.Errorf("len(args) = %d, want 3", len(b.args))
+	}
+}
+
+func TestKeysetAfter(t *testing.T) {
+	c := &pagination.Cursor{Value: 100.0, ID: "abc"}
+	tests := []struct {
+		name    string
+		k       keyset
+		argsIn  int // сколько параметров уже занято фильтрами
+		want    string
+		wantBy  string
+		wantArg []interface{}
+	}{
+		{
+			name:    "ascending, first params",
+			k:       keyset{name: "price_asc", key: "price", id: "id"},
+			want:    "(price, id) > ($1, $2)",
+			wantBy:  "price ASC, id ASC",
+			wantArg: []interface{}{100.0, "abc"},
+		},
+		{
+			name:    "descending after filter params",
+			k:       keyset{name: "newest", key: "created_at", id: "id", desc: true},
+			argsIn:  2,
+			want:    "(created_at, id) < ($3, $4)",
+			wantBy:  "created_at DESC, id DESC",
+			wantArg: []interface{}{"f1", "f2", 100.0, "abc"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &sqlBuilder{}
+			for i := 0; i < tt.argsIn; i++ {
+				b.arg([]string{"f1", "f2"}[i])
+			}
+			if got := tt.k.after(b, c); got != tt.want {
+				t.Errorf("after = %q, want %q", got, tt.want)
+			}
+			if got := tt.k.orderBy(); got != tt.wantBy {
+				t.Errorf("orderBy = %q, want %q", got, tt.wantBy)
+			}
+			if len(b.args) != len(tt.wantArg) {
+				t.Fatalf("args = %v, want %v", b.args, tt.wantArg)
+			}
+			for i := range b.args {
+				if b.args[i] != tt.wantArg[i] {
+					t.Errorf("args[%d] = %v, want %v", i, b.args[i], tt.wantArg[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPageQueryCursorSort(t *testing.T) {
+	listings := pageQuery{scope: "listings", order: newestFirst}
+	reviews := pageQuery{scope: "reviews", order: reviewSorts["newest"].keyset}
+	if listings.cursorSort() == reviews.cursorSort() {
+		t.Errorf("listings and reviews share cursor sort %q", listings.cursorSort())
+	}
+	if got := listings.cursorSort(); got != "listings:newest" {
+		t.Errorf("cursorSort = %q, want listings:newest", got)
+	}
+}
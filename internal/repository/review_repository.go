@@ -2,98 +2,625 @@ package repository
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"listing-service/internal/model"
+	"listing-service/internal/pagination"
 )
 
+var (
+	// ErrDuplicateReview is returned by Insert when the user already reviewed the listing.
+	ErrDuplicateReview = errors.New("review already exists")
+	// ErrDuplicateReport is returned by InsertReport when the user already has an open report on the review.
+	ErrDuplicateReport = errors.New("review already reported")
+	// ErrTooManyPhotos is returned by AddPhoto when the review already has the maximum number of photos.
+	ErrTooManyPhotos = errors.New("too many photos")
+)
+
+// reviewColumns are the columns scanned into model.Review.
+const reviewColumns = `id, listing_id, user_id, rating, comment, created_at, updated_at, helpful_count,
+	reply_text, reply_created_at, reply_updated_at, hidden_at, hidden_by`
+
 type ReviewRepository struct {
-	db *sqlx.DB
+	db    *sqlx.DB
+	prior RatingPrior
 }
 
-func NewReviewRepository(db *sqlx.DB) *ReviewRepository {
-	return &ReviewRepository{db: db}
+func NewReviewRepository(db *sqlx.DB, prior RatingPrior) *ReviewRepository {
+	return &ReviewRepository{db: db, prior: prior}
+}
+
+// RatingPrior is the prior of the Bayesian average stored in listings.rating_score:
+//
+//	rating_score = (Weight*Mean + rating_sum) / (Weight + review_count)
+//
+// A listing needs about Weight reviews before its own ratings outweigh Mean,
+// so one 5-star review no longer beats hundreds of 4.8s.
+type RatingPrior struct {
+	Mean   float64
+	Weight float64
+}
+
+// DefaultRatingPrior is used when RATING_PRIOR_MEAN / RATING_PRIOR_WEIGHT are not set.
+// Migration 0011 backfills rating_score with the same values.
+var DefaultRatingPrior = RatingPrior{Mean: 3.5, Weight: 10}
+
+// ParseRatingPrior builds a RatingPrior from its configuration strings;
+// empty values fall back to DefaultRatingPrior.
+func ParseRatingPrior(mean, weight string) (RatingPrior, error) {
+	p := DefaultRatingPrior
+	if mean != "" {
+		v, err := strconv.ParseFloat(mean, 64)
+		if err != nil || v < 1 || v > 5 {
+			return p, fmt.Errorf("invalid rating prior mean %q: must be between 1 and 5", mean)
+		}
+		p.Mean = v
+	}
+	if weight != "" {
+		v, err := strconv.ParseFloat(weight, 64)
+		if err != nil || v < 0 {
+			return p, fmt.Errorf("invalid rating prior weight %q: must be non-negative", weight)
+		}
+		p.Weight = v
+	}
+	return p, nil
 }
 
-// Insert saves a new review and returns its generated ID (as a string) and created_at.
-// In this example, the reviews.id column is still a BIGSERIAL (int64),
-// so we scan into an int64 and then convert to string.
-// If you have already migrated to a UUID column, you can scan into a string directly.
+// Insert saves a new review and, in the same transaction, adds its rating to
+// the listing's aggregates. It sets the generated ID and created_at on review.
 func (r *ReviewRepository) Insert(ctx context.Context, review *model.Review) (string, error) {
 	const insertQuery = `
         INSERT INTO reviews (listing_id, user_id, rating, comment)
         VALUES ($1, $2, $3, $4)
         RETURNING id, created_at
     `
-	// 1) Так как в БД id хранится как UUID (или TEXT), считываем его в string
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("ReviewRepository.Insert: %w", err)
+	}
+	defer tx.Rollback()
+
+	// В БД id хранится как UUID (или TEXT), считываем его в string
 	var newID string
 	var createdAt time.Time
 
-	err := r.db.QueryRowxContext(ctx, insertQuery,
+	err = tx.QueryRowxContext(ctx, insertQuery,
 		review.ListingID, // это строка (UUID) из listings.id
 		review.UserID,
 		review.Rating,
 		review.Comment,
 	).Scan(&newID, &createdAt)
 	if err != nil {
+		// reviews_listing_user_uniq: one review per user per listing
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return "", fmt.Errorf("ReviewRepository.Insert: %w", ErrDuplicateReview)
+		}
+		return "", fmt.Errorf("ReviewRepository.Insert: %w", err)
+	}
+	if err := r.adjustAggregates(ctx, tx, review.ListingID, review.Rating, 1); err != nil {
 		return "", fmt.Errorf("ReviewRepository.Insert: %w", err)
 	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("ReviewRepository.Insert commit: %w", err)
+	}
 
 	review.ID = newID // сохраняем строковый UUID
 	review.CreatedAt = createdAt
 	return newID, nil
 }
 
-// FindByListing returns all reviews for a given listingID (string), sorted by created_at DESC.
-func (r *ReviewRepository) FindByListing(ctx context.Context, listingID string) ([]model.Review, error) {
-	const selectQuery = `
-		SELECT id, listing_id, user_id, rating, comment, created_at
-		FROM reviews
-		WHERE listing_id = $1
-		ORDER BY created_at DESC
-	`
-	var reviews []model.Review
-	if err := r.db.SelectContext(ctx, &reviews, selectQuery, listingID); err != nil {
+// ReviewFilter narrows and orders the reviews returned by FindByListing.
+type ReviewFilter struct {
+	Sort    string // one of reviewSorts; empty means newest
+	Ratings []int  // star values to keep; empty keeps all
+	Viewer  string // user whose helpful votes fill Review.VotedByMe; empty for anonymous
+}
+
+// reviewSort is a review ordering plus the accessor for its cursor value.
+type reviewSort struct {
+	keyset
+	value func(rv *model.Review) interface{}
+}
+
+// reviewSorts is the whitelist of review orderings. The id tie-breaker keeps
+// pages stable when many reviews share the same rating or helpful count.
+var reviewSorts = map[string]reviewSort{
+	"newest": {newestFirst,
+		func(rv *model.Review) interface{} { return rv.CreatedAt }},
+	"highest": {keyset{name: "highest", key: "rating", id: "id", desc: true},
+		func(rv *model.Review) interface{} { return rv.Rating }},
+	"lowest": {keyset{name: "lowest", key: "rating", id: "id"},
+		func(rv *model.Review) interface{} { return rv.Rating }},
+	"most_helpful": {keyset{name: "most_helpful", key: "helpful_count", id: "id", desc: true},
+		func(rv *model.Review) interface{} { return rv.HelpfulCount }},
+}
+
+// IsReviewSort reports whether name is a supported review ordering.
+func IsReviewSort(name string) bool {
+	_, ok := reviewSorts[name]
+	return ok
+}
+
+// FindByListing returns one page of visible reviews for a given listingID
+// (string), filtered and ordered by f (newest first by default).
+func (r *ReviewRepository) FindByListing(ctx context.Context, listingID string, f ReviewFilter, page pagination.Params) (*pagination.Page[model.Review], error) {
+	order, ok := reviewSorts[f.Sort]
+	if !ok {
+		order = reviewSorts["newest"]
+	}
+
+	b := &sqlBuilder{}
+	where := "listing_id = " + b.arg(listingID) + " AND hidden_at IS NULL"
+	if len(f.Ratings) > 0 {
+		where += " AND rating = ANY(" + b.arg(pq.Array(f.Ratings)) + ")"
+	}
+	q := pageQuery{
+		b:     b,
+		scope: "reviews",
+		from:  "reviews",
+		where: where,
+		order: order.keyset,
+	}
+	q.whereArgc = len(b.args)
+
+	q.columns = reviewColumns + ", false AS voted_by_me"
+	if f.Viewer != "" {
+		q.columns = reviewColumns + `, EXISTS (
+			SELECT 1 FROM review_votes v
+			WHERE v.review_id = reviews.id::text AND v.user_id = ` + b.arg(f.Viewer) + `
+		) AS voted_by_me`
+	}
+
+	reviews, err := selectPage(ctx, r.db, q, page, func(rv *model.Review) (interface{}, string) {
+		return order.value(rv), rv.ID
+	})
+	if err != nil {
 		return nil, fmt.Errorf("ReviewRepository.FindByListing: %w", err)
 	}
 	return reviews, nil
 }
 
-// RecalcAverage recalculates AVG(rating) for a listingID (string) and updates listings.average_rating.
-func (r *ReviewRepository) RecalcAverage(ctx context.Context, listingID string) error {
+// FindByID returns a single review, or sql.ErrNoRows if it does not exist.
+func (r *ReviewRepository) FindByID(ctx context.Context, id string) (*model.Review, error) {
+	var review model.Review
+	if err := r.db.GetContext(ctx, &review, `SELECT `+reviewColumns+` FROM reviews WHERE id = $1`, id); err != nil {
+		return nil, fmt.Errorf("ReviewRepository.FindByID: %w", err)
+	}
+	return &review, nil
+}
+
+// Update changes the rating and comment of a review, stamps updated_at and
+// moves the review from its old rating to the new one in the listing's aggregates.
+func (r *ReviewRepository) Update(ctx context.Context, review *model.Review) error {
+	const updateQuery = `
+		UPDATE reviews
+		SET rating = $1, comment = $2, updated_at = now()
+		WHERE id = $3
+		RETURNING updated_at
+	`
 	tx, err := r.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("ReviewRepository.BeginTxx: %w", err)
+		return fmt.Errorf("ReviewRepository.Update: %w", err)
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
+	defer tx.Rollback()
+
+	// The stored rating, not the caller's copy, is what the aggregates contain.
+	var old struct {
+		Rating int  `db:"rating"`
+		Hidden bool `db:"hidden"`
+	}
+	const oldQuery = `SELECT rating, hidden_at IS NOT NULL AS hidden FROM reviews WHERE id = $1 FOR UPDATE`
+	if err := tx.GetContext(ctx, &old, oldQuery, review.ID); err != nil {
+		return fmt.Errorf("ReviewRepository.Update: %w", err)
+	}
+	if err := tx.QueryRowxContext(ctx, updateQuery, review.Rating, review.Comment, review.ID).Scan(&review.UpdatedAt); err != nil {
+		return fmt.Errorf("ReviewRepository.Update: %w", err)
+	}
+	if !old.Hidden && old.Rating != review.Rating {
+		if err := r.adjustAggregates(ctx, tx, review.ListingID, old.Rating, -1); err != nil {
+			return fmt.Errorf("ReviewRepository.Update: %w", err)
+		}
+		if err := r.adjustAggregates(ctx, tx, review.ListingID, review.Rating, 1); err != nil {
+			return fmt.Errorf("ReviewRepository.Update: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ReviewRepository.Update commit: %w", err)
+	}
+	return nil
+}
+
+// Delete removes a review with its helpful votes, reports and photo rows and,
+// unless the review was hidden, subtracts its rating from the listing's
+// aggregates. The photo files themselves are deleted by the caller.
+func (r *ReviewRepository) Delete(ctx context.Context, id string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ReviewRepository.Delete: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deleted struct {
+		ListingID string `db:"listing_id"`
+		Rating    int    `db:"rating"`
+		Hidden    bool   `db:"hidden"`
+	}
+	const deleteQuery = `DELETE FROM reviews WHERE id = $1 RETURNING listing_id, rating, hidden_at IS NOT NULL AS hidden`
+	err = tx.GetContext(ctx, &deleted, deleteQuery, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("ReviewRepository.Delete: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM review_votes WHERE review_id = $1`, id); err != nil {
+		return fmt.Errorf("ReviewRepository.Delete votes: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM review_reports WHERE review_id = $1`, id); err != nil {
+		return fmt.Errorf("ReviewRepository.Delete reports: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM review_photos WHERE review_id = $1`, id); err != nil {
+		return fmt.Errorf("ReviewRepository.Delete photos: %w", err)
+	}
+	if !deleted.Hidden {
+		if err := r.adjustAggregates(ctx, tx, deleted.ListingID, deleted.Rating, -1); err != nil {
+			return fmt.Errorf("ReviewRepository.Delete: %w", err)
 		}
-	}()
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ReviewRepository.Delete commit: %w", err)
+	}
+	return nil
+}
 
-	var avg float64
-	const avgQuery = `
-		SELECT COALESCE(AVG(rating)::numeric(3,2), 0)
-		FROM reviews
-		WHERE listing_id = $1
+// AddVote records userID's helpful vote on a review and returns the review's
+// new helpful_count. Voting twice is a no-op.
+func (r *ReviewRepository) AddVote(ctx context.Context, reviewID, userID string) (int, error) {
+	const insertQuery = `
+		INSERT INTO review_votes (review_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (review_id, user_id) DO NOTHING
 	`
-	if err := tx.GetContext(ctx, &avg, avgQuery, listingID); err != nil {
-		return fmt.Errorf("ReviewRepository get avg: %w", err)
+	return r.changeVote(ctx, reviewID, userID, insertQuery, 1)
+}
+
+// RemoveVote withdraws userID's helpful vote and returns the review's new
+// helpful_count. Removing a missing vote is a no-op.
+func (r *ReviewRepository) RemoveVote(ctx context.Context, reviewID, userID string) (int, error) {
+	const deleteQuery = `DELETE FROM review_votes WHERE review_id = $1 AND user_id = $2`
+	return r.changeVote(ctx, reviewID, userID, deleteQuery, -1)
+}
+
+// HasVoted reports whether userID has a helpful vote on the review.
+func (r *ReviewRepository) HasVoted(ctx context.Context, reviewID, userID string) (bool, error) {
+	var voted bool
+	const q = `SELECT EXISTS (SELECT 1 FROM review_votes WHERE review_id = $1 AND user_id = $2)`
+	if err := r.db.GetContext(ctx, &voted, q, reviewID, userID); err != nil {
+		return false, fmt.Errorf("ReviewRepository.HasVoted: %w", err)
 	}
+	return voted, nil
+}
 
-	const updateQuery = `
-		UPDATE listings
-		SET average_rating = $1
+// changeVote runs voteQuery and, if it changed a row, moves helpful_count by
+// delta in the same transaction.
+func (r *ReviewRepository) changeVote(ctx context.Context, reviewID, userID, voteQuery string, delta int) (int, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("ReviewRepository.changeVote: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, voteQuery, reviewID, userID)
+	if err != nil {
+		return 0, fmt.Errorf("ReviewRepository.changeVote: %w", err)
+	}
+	changed, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ReviewRepository.changeVote: %w", err)
+	}
+	if changed == 0 {
+		delta = 0
+	}
+
+	var count int
+	const countQuery = `UPDATE reviews SET helpful_count = helpful_count + $1 WHERE id = $2 RETURNING helpful_count`
+	if err := tx.GetContext(ctx, &count, countQuery, delta, reviewID); err != nil {
+		return 0, fmt.Errorf("ReviewRepository.changeVote count: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("ReviewRepository.changeVote commit: %w", err)
+	}
+	return count, nil
+}
+
+// SetReply creates or replaces the owner's reply on a review. reply_created_at
+// keeps the time of the first reply; reply_updated_at is set only on edits.
+func (r *ReviewRepository) SetReply(ctx context.Context, review *model.Review, text string) error {
+	const replyQuery = `
+		UPDATE reviews
+		SET reply_text       = $1,
+		    reply_updated_at = CASE WHEN reply_text IS NULL THEN NULL ELSE now() END,
+		    reply_created_at = COALESCE(reply_created_at, now())
 		WHERE id = $2
+		RETURNING reply_text, reply_created_at, reply_updated_at
+	`
+	err := r.db.QueryRowxContext(ctx, replyQuery, text, review.ID).
+		Scan(&review.ReplyText, &review.ReplyCreatedAt, &review.ReplyUpdatedAt)
+	if err != nil {
+		return fmt.Errorf("ReviewRepository.SetReply: %w", err)
+	}
+	return nil
+}
+
+// DeleteReply removes the owner's reply from a review.
+func (r *ReviewRepository) DeleteReply(ctx context.Context, reviewID string) error {
+	const deleteQuery = `
+		UPDATE reviews
+		SET reply_text = NULL, reply_created_at = NULL, reply_updated_at = NULL
+		WHERE id = $1
+	`
+	if _, err := r.db.ExecContext(ctx, deleteQuery, reviewID); err != nil {
+		return fmt.Errorf("ReviewRepository.DeleteReply: %w", err)
+	}
+	return nil
+}
+
+// reviewPhotoColumns are the columns scanned into model.ReviewPhoto.
+const reviewPhotoColumns = "id, review_id, file_id, thumb_file_id, medium_file_id, large_file_id, created_at"
+
+// AddPhoto attaches an uploaded file to a review unless the review already has
+// max photos. The review row is locked so concurrent uploads cannot exceed max.
+func (r *ReviewRepository) AddPhoto(ctx context.Context, photo *model.ReviewPhoto, max int) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ReviewRepository.AddPhoto: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT 1 FROM reviews WHERE id = $1 FOR UPDATE`, photo.ReviewID); err != nil {
+		return fmt.Errorf("ReviewRepository.AddPhoto lock: %w", err)
+	}
+	var count int
+	if err := tx.GetContext(ctx, &count, `SELECT COUNT(*) FROM review_photos WHERE review_id = $1`, photo.ReviewID); err != nil {
+		return fmt.Errorf("ReviewRepository.AddPhoto count: %w", err)
+	}
+	if count >= max {
+		return fmt.Errorf("ReviewRepository.AddPhoto: %w", ErrTooManyPhotos)
+	}
+
+	const insertQuery = `
+		INSERT INTO review_photos (review_id, file_id, thumb_file_id, medium_file_id, large_file_id)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+	err = tx.QueryRowxContext(ctx, insertQuery, photo.ReviewID, photo.FileID,
+		photo.ThumbFileID, photo.MediumFileID, photo.LargeFileID,
+	).Scan(&photo.ID, &photo.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ReviewRepository.AddPhoto: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ReviewRepository.AddPhoto commit: %w", err)
+	}
+	return nil
+}
+
+// FindPhotos returns the photos of the given reviews keyed by review id, oldest first.
+func (r *ReviewRepository) FindPhotos(ctx context.Context, reviewIDs []string) (map[string][]model.ReviewPhoto, error) {
+	out := map[string][]model.ReviewPhoto{}
+	if len(reviewIDs) == 0 {
+		return out, nil
+	}
+
+	var photos []model.ReviewPhoto
+	query := `SELECT ` + reviewPhotoColumns + ` FROM review_photos WHERE review_id = ANY($1) ORDER BY id`
+	if err := r.db.SelectContext(ctx, &photos, query, pq.Array(reviewIDs)); err != nil {
+		return nil, fmt.Errorf("ReviewRepository.FindPhotos: %w", err)
+	}
+	for _, p := range photos {
+		out[p.ReviewID] = append(out[p.ReviewID], p)
+	}
+	return out, nil
+}
+
+// DeletePhoto removes a review photo row. The file itself is deleted by the caller.
+func (r *ReviewRepository) DeletePhoto(ctx context.Context, id string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM review_photos WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("ReviewRepository.DeletePhoto: %w", err)
+	}
+	return nil
+}
+
+// InsertReport saves an abuse report on a review.
+func (r *ReviewRepository) InsertReport(ctx context.Context, report *model.ReviewReport) error {
+	const insertQuery = `
+		INSERT INTO review_reports (review_id, reporter_id, reason, note)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+	err := r.db.QueryRowxContext(ctx, insertQuery, report.ReviewID, report.ReporterID, report.Reason, report.Note).
+		Scan(&report.ID, &report.CreatedAt)
+	if err != nil {
+		// review_reports_open_uniq: one open report per user per review
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return fmt.Errorf("ReviewRepository.InsertReport: %w", ErrDuplicateReport)
+		}
+		return fmt.Errorf("ReviewRepository.InsertReport: %w", err)
+	}
+	return nil
+}
+
+// reportedFrom joins reviews to the summary of their open reports; only
+// reviews with at least one open report are matched.
+const reportedFrom = `reviews JOIN (
+		SELECT review_id, COUNT(*) AS report_count, MAX(created_at) AS last_reported_at
+		FROM review_reports
+		WHERE resolved_at IS NULL
+		GROUP BY review_id
+	) rr ON rr.review_id = reviews.id::text`
+
+// onLiveListing keeps only reviews whose listing is not soft-deleted. It is a
+// semi-join: joining listings directly would make the review columns ambiguous.
+const onLiveListing = `EXISTS (
+		SELECT 1 FROM listings l WHERE l.id = reviews.listing_id AND l.deleted_at IS NULL
+	)`
+
+// FindReported returns one page of the admin moderation queue: reviews with
+// open reports, most recently reported first. Reviews of soft-deleted listings
+// are left out, as everywhere else.
+func (r *ReviewRepository) FindReported(ctx context.Context, page pagination.Params) (*pagination.Page[model.ReportedReview], error) {
+	q := pageQuery{
+		b:       &sqlBuilder{},
+		scope:   "reported",
+		from:    reportedFrom,
+		columns: reviewColumns + ", rr.report_count, rr.last_reported_at",
+		where:   onLiveListing,
+		order:   keyset{name: "reported", key: "rr.last_reported_at", id: "reviews.id", desc: true},
+	}
+
+	reviews, err := selectPage(ctx, r.db, q, page, func(rv *model.ReportedReview) (interface{}, string) {
+		return rv.LastReportedAt, rv.ID
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ReviewRepository.FindReported: %w", err)
+	}
+	return reviews, nil
+}
+
+// SetHidden hides (hidden = true) or restores a review on behalf of adminID,
+// resolving its open reports and moving its rating out of or back into the
+// listing's aggregates in one transaction. Hiding a hidden review or
+// restoring a visible one only resolves the reports.
+func (r *ReviewRepository) SetHidden(ctx context.Context, reviewID, adminID string, hidden bool) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("ReviewRepository.SetHidden: %w", err)
+	}
+	defer tx.Rollback()
+
+	var changed struct {
+		ListingID string `db:"listing_id"`
+		Rating    int    `db:"rating"`
+	}
+	const hideQuery = `
+		UPDATE reviews
+		SET hidden_at = CASE WHEN $2 THEN now() END,
+		    hidden_by = CASE WHEN $2 THEN $3 END
+		WHERE id = $1 AND (hidden_at IS NOT NULL) <> $2
+		RETURNING listing_id, rating
 	`
-	if _, err = tx.ExecContext(ctx, updateQuery, avg, listingID); err != nil {
-		return fmt.Errorf("ReviewRepository update avg: %w", err)
+	err = tx.GetContext(ctx, &changed, hideQuery, reviewID, hidden, adminID)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		// already in the requested state
+	case err != nil:
+		return fmt.Errorf("ReviewRepository.SetHidden: %w", err)
+	default:
+		delta := 1
+		if hidden {
+			delta = -1
+		}
+		if err := r.adjustAggregates(ctx, tx, changed.ListingID, changed.Rating, delta); err != nil {
+			return fmt.Errorf("ReviewRepository.SetHidden: %w", err)
+		}
 	}
 
-	if err = tx.Commit(); err != nil {
-		return fmt.Errorf("ReviewRepository commit: %w", err)
+	const resolveQuery = `
+		UPDATE review_reports
+		SET resolved_at = now(), resolved_by = $2
+		WHERE review_id = $1 AND resolved_at IS NULL
+	`
+	if _, err := tx.ExecContext(ctx, resolveQuery, reviewID, adminID); err != nil {
+		return fmt.Errorf("ReviewRepository.SetHidden resolve reports: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("ReviewRepository.SetHidden commit: %w", err)
 	}
 	return nil
 }
+
+// adjustAggregates adds (delta = 1) or removes (delta = -1) one review with the
+// given rating from the listing's running aggregates. The row lock taken by the
+// UPDATE serialises concurrent review changes on the same listing, so the
+// counters never lose an increment.
+func (r *ReviewRepository) adjustAggregates(ctx context.Context, tx *sqlx.Tx, listingID string, rating, delta int) error {
+	const adjustQuery = `
+		UPDATE listings
+		SET review_count   = review_count + $2,
+		    rating_sum     = rating_sum + $2 * $3,
+		    average_rating = CASE WHEN review_count + $2 > 0
+		                          THEN round((rating_sum + $2 * $3)::numeric / (review_count + $2), 2)
+		                          ELSE 0 END,
+		    rating_score   = COALESCE(($4::float8 * $5::float8 + (rating_sum + $2 * $3))
+		                              / NULLIF($5 + (review_count + $2), 0), $4::float8),
+		    rating_1       = rating_1 + CASE WHEN $3 = 1 THEN $2 ELSE 0 END,
+		    rating_2       = rating_2 + CASE WHEN $3 = 2 THEN $2 ELSE 0 END,
+		    rating_3       = rating_3 + CASE WHEN $3 = 3 THEN $2 ELSE 0 END,
+		    rating_4       = rating_4 + CASE WHEN $3 = 4 THEN $2 ELSE 0 END,
+		    rating_5       = rating_5 + CASE WHEN $3 = 5 THEN $2 ELSE 0 END
+		WHERE id = $1
+	`
+	_, err := tx.ExecContext(ctx, adjustQuery, listingID, delta, rating, r.prior.Mean, r.prior.Weight)
+	if err != nil {
+		return fmt.Errorf("adjust aggregates: %w", err)
+	}
+	return nil
+}
+
+// aggregatesQuery recomputes the review aggregates of every listing from its
+// visible reviews ($1, $2 — the rating prior) and touches only rows that differ.
+const aggregatesQuery = `
+	UPDATE listings l
+	SET average_rating = a.avg,
+	    rating_score   = a.score,
+	    review_count   = a.total,
+	    rating_sum     = a.sum,
+	    rating_1       = a.r1,
+	    rating_2       = a.r2,
+	    rating_3       = a.r3,
+	    rating_4       = a.r4,
+	    rating_5       = a.r5
+	FROM (
+		SELECT ls.id,
+		       COALESCE(round(AVG(rv.rating), 2), 0)     AS avg,
+		       COUNT(rv.id)                              AS total,
+		       COALESCE(SUM(rv.rating), 0)               AS sum,
+		       COALESCE(($1::float8 * $2::float8 + COALESCE(SUM(rv.rating), 0))
+		                / NULLIF($2 + COUNT(rv.id), 0), $1::float8) AS score,
+		       COUNT(rv.id) FILTER (WHERE rv.rating = 1) AS r1,
+		       COUNT(rv.id) FILTER (WHERE rv.rating = 2) AS r2,
+		       COUNT(rv.id) FILTER (WHERE rv.rating = 3) AS r3,
+		       COUNT(rv.id) FILTER (WHERE rv.rating = 4) AS r4,
+		       COUNT(rv.id) FILTER (WHERE rv.rating = 5) AS r5
+		FROM listings ls
+		LEFT JOIN reviews rv ON rv.listing_id = ls.id AND rv.hidden_at IS NULL
+		GROUP BY ls.id
+	) a
+	WHERE l.id = a.id
+	  AND (l.average_rating, l.rating_score, l.review_count, l.rating_sum,
+	       l.rating_1, l.rating_2, l.rating_3, l.rating_4, l.rating_5)
+	      IS DISTINCT FROM (a.avg, a.score, a.total, a.sum, a.r1, a.r2, a.r3, a.r4, a.r5)
+`
+
+// RecalcAll recomputes the review aggregates of every listing from scratch and
+// returns how many listings had drifted. It is meant for the repair-ratings
+// command, not for the request path; run it also after changing the rating prior.
+func (r *ReviewRepository) RecalcAll(ctx context.Context) (int64, error) {
+	res, err := r.db.ExecContext(ctx, aggregatesQuery, r.prior.Mean, r.prior.Weight)
+	if err != nil {
+		return 0, fmt.Errorf("ReviewRepository.RecalcAll: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("ReviewRepository.RecalcAll: %w", err)
+	}
+	return n, nil
+}
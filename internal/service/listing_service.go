@@ -0,0 +1,446 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"listing-service/internal/model"
+	"listing-service/internal/pagination"
+	"listing-service/internal/repository"
+)
+
+var (
+	// ErrListingNotFound — объявления с таким ID нет.
+	ErrListingNotFound = errors.New("listing not found")
+	// ErrInvalidTransition — переход между статусами не предусмотрен жизненным циклом.
+	ErrInvalidTransition = errors.New("invalid status transition")
+	// ErrForbidden — переход разрешён, но не этому пользователю.
+	ErrForbidden = errors.New("forbidden")
+	// ErrInvalidReason — неизвестный код причины отклонения.
+	ErrInvalidReason = errors.New("invalid rejection reason")
+)
+
+// Actor — пользователь, выполняющий действие (из JWT).
+type Actor struct {
+	UserID  string
+	IsAdmin bool
+}
+
+// role — в каком качестве Actor действует над конкретным объявлением.
+type role int
+
+const (
+	roleOwner role = 1 << iota
+	roleAdmin
+)
+
+// roles возвращает набор ролей actor по отношению к объявлению l.
+func (a Actor) roles(l *model.Listing) role {
+	var r role
+	if a.UserID != "" && a.UserID == l.OwnerID {
+		r |= roleOwner
+	}
+	if a.IsAdmin {
+		r |= roleAdmin
+	}
+	return r
+}
+
+// CanManage сообщает, является ли actor владельцем объявления l или админом.
+func (a Actor) CanManage(l *model.Listing) bool {
+	return a.roles(l) != 0
+}
+
+// CanView сообщает, может ли actor видеть объявление l: опубликованные видны
+// всем, остальные — только владельцу и админам.
+func (a Actor) CanView(l *model.Listing) bool {
+	return l.Status == model.StatusApproved || a.CanManage(l)
+}
+
+// findVisible загружает объявление id, если actor может его видеть.
+// Невидимое объявление не отличается от отсутствующего: ErrListingNotFound.
+func findVisible(ctx context.Context, lr *repository.ListingRepository, id string, actor Actor) (*model.Listing, error) {
+	l, err := lr.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrListingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanView(l) {
+		return nil, ErrListingNotFound
+	}
+	return l, nil
+}
+
+// CanOwn сообщает, может ли actor назначить владельцем объявления ownerID:
+// обычный пользователь — только себя, админ — кого угодно.
+func (a Actor) CanOwn(ownerID string) bool {
+	return a.IsAdmin || (a.UserID != "" && a.UserID == ownerID)
+}
+
+// transitions — жизненный цикл объявления:
+//
+//	draft → pending → approved/rejected → archived/sold/expired
+//
+// Для каждого перехода указано, кто может его выполнить. Модерация
+// (approved/rejected) доступна только админам; владелец может отправить
+// черновик или отклонённое объявление на модерацию и снять объявление с публикации.
+var transitions = map[string]map[string]role{
+	model.StatusDraft: {
+		model.StatusPending:  roleOwner | roleAdmin,
+		model.StatusArchived: roleOwner | roleAdmin,
+	},
+	model.StatusPending: {
+		model.StatusApproved: roleAdmin,
+		model.StatusRejected: roleAdmin,
+		model.StatusDraft:    roleOwner,
+	},
+	model.StatusApproved: {
+		model.StatusArchived: roleOwner | roleAdmin,
+		model.StatusSold:     roleOwner | roleAdmin,
+		model.StatusExpired:  roleAdmin,
+	},
+	model.StatusRejected: {
+		model.StatusPending:  roleOwner,
+		model.StatusDraft:    roleOwner,
+		model.StatusArchived: roleOwner | roleAdmin,
+	},
+	model.StatusArchived: {
+		model.StatusDraft: roleOwner,
+	},
+	model.StatusExpired: {
+		model.StatusPending:  roleOwner,
+		model.StatusArchived: roleOwner | roleAdmin,
+	},
+}
+
+// ListingService содержит бизнес-логику объявлений: жизненный цикл, права на
+// переходы и запись истории изменений.
+type ListingService struct {
+	listingRepo *repository.ListingRepository
+	eventRepo   *repository.ListingEventRepository
+}
+
+// NewListingService создаёт ListingService.
+func NewListingService(lr *repository.ListingRepository, er *repository.ListingEventRepository) *ListingService {
+	return &ListingService{listingRepo: lr, eventRepo: er}
+}
+
+// InitialStatus проверяет статус, с которым клиент создаёт объявление.
+// Новое объявление может быть только черновиком или сразу уйти на модерацию;
+// без статуса оно отправляется на модерацию.
+func InitialStatus(requested string) (string, error) {
+	switch requested {
+	case "":
+		return model.StatusPending, nil
+	case model.StatusDraft, model.StatusPending:
+		return requested, nil
+	default:
+		return "", fmt.Errorf("%w: new listing cannot be %q", ErrInvalidTransition, requested)
+	}
+}
+
+// Create сохраняет новое объявление с допустимым начальным статусом.
+func (s *ListingService) Create(ctx context.Context, l *model.Listing, actor Actor) error {
+	if !actor.CanOwn(l.OwnerID) {
+		return fmt.Errorf("%w: cannot create listings for another user", ErrForbidden)
+	}
+	status, err := InitialStatus(l.Status)
+	if err != nil {
+		return err
+	}
+	l.Status = status
+	return s.listingRepo.InTx(ctx, s.eventRepo, func(lr *repository.ListingRepository, er *repository.ListingEventRepository) error {
+		if err := lr.Create(ctx, l); err != nil {
+			return fmt.Errorf("ListingService.Create: %w", err)
+		}
+		return record(ctx, er, l.ID, actor, model.EventCreated, nil, l)
+	})
+}
+
+// Update сохраняет изменённые поля объявления l и, если status не пуст и
+// отличается от текущего, выполняет переход по жизненному циклу.
+// Редактировать может только владелец или админ; недопустимый переход
+// проверяется до записи полей, а поля, статус и история пишутся в одной транзакции.
+// Если владелец меняет содержимое одобренного или отклонённого объявления и не
+// просит другой статус, объявление возвращается на модерацию.
+func (s *ListingService) Update(ctx context.Context, l *model.Listing, status string, actor Actor) error {
+	old, err := s.get(ctx, l.ID)
+	if err != nil {
+		return err
+	}
+	if !actor.CanManage(old) {
+		return fmt.Errorf("%w: only the owner can edit this listing", ErrForbidden)
+	}
+	if !actor.CanOwn(l.OwnerID) {
+		return fmt.Errorf("%w: cannot transfer the listing to another user", ErrForbidden)
+	}
+	l.Status = old.Status
+
+	changeStatus := status != "" && status != old.Status
+	if changeStatus {
+		if err := checkTransition(old, status, actor); err != nil {
+			return err
+		}
+	}
+	return s.listingRepo.InTx(ctx, s.eventRepo, func(lr *repository.ListingRepository, er *repository.ListingEventRepository) error {
+		if err := lr.Update(ctx, l); err != nil {
+			return fmt.Errorf("ListingService.Update: %w", err)
+		}
+		oldFields, newFields := diff(old, l)
+		if len(newFields) > 0 {
+			if err := record(ctx, er, l.ID, actor, model.EventUpdated, oldFields, newFields); err != nil {
+				return err
+			}
+		}
+		switch {
+		case changeStatus:
+			return setStatus(ctx, lr, er, l, status, actor)
+		case len(newFields) > 0 && needsRemoderation(old, actor):
+			return setStatus(ctx, lr, er, l, model.StatusPending, actor)
+		}
+		return nil
+	})
+}
+
+// needsRemoderation сообщает, нужно ли после правки содержимого снова отправить
+// объявление на модерацию: решение модератора относилось к прежнему тексту.
+// Правки админа модерацию не сбрасывают.
+func needsRemoderation(l *model.Listing, actor Actor) bool {
+	if actor.IsAdmin {
+		return false
+	}
+	return l.Status == model.StatusApproved || l.Status == model.StatusRejected
+}
+
+// Delete удаляет объявление и сохраняет в истории его последний снимок.
+// Удалить может только владелец или админ.
+func (s *ListingService) Delete(ctx context.Context, id string, actor Actor) error {
+	l, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !actor.CanManage(l) {
+		return fmt.Errorf("%w: only the owner can delete this listing", ErrForbidden)
+	}
+	return s.listingRepo.InTx(ctx, s.eventRepo, func(lr *repository.ListingRepository, er *repository.ListingEventRepository) error {
+		if err := lr.Delete(ctx, id); err != nil {
+			return fmt.Errorf("ListingService.Delete: %w", err)
+		}
+		return record(ctx, er, id, actor, model.EventDeleted, l, nil)
+	})
+}
+
+// Restore восстанавливает мягко удалённое объявление.
+func (s *ListingService) Restore(ctx context.Context, id string, actor Actor) (*model.Listing, error) {
+	err := s.listingRepo.InTx(ctx, s.eventRepo, func(lr *repository.ListingRepository, er *repository.ListingEventRepository) error {
+		ok, err := lr.Restore(ctx, id)
+		if err != nil {
+			return fmt.Errorf("ListingService.Restore: %w", err)
+		}
+		if !ok {
+			return ErrListingNotFound
+		}
+		return record(ctx, er, id, actor, model.EventRestored, nil, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.get(ctx, id)
+}
+
+// Transition переводит объявление id в статус to от имени actor.
+func (s *ListingService) Transition(ctx context.Context, id, to string, actor Actor) (*model.Listing, error) {
+	l, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTransition(l, to, actor); err != nil {
+		return nil, err
+	}
+	err = s.listingRepo.InTx(ctx, s.eventRepo, func(lr *repository.ListingRepository, er *repository.ListingEventRepository) error {
+		return setStatus(ctx, lr, er, l, to, actor)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// History возвращает страницу истории объявления, от новых событий к старым.
+func (s *ListingService) History(ctx context.Context, id string, page pagination.Params) (*pagination.Page[model.ListingEvent], error) {
+	events, err := s.eventRepo.FindByListing(ctx, id, page)
+	if err != nil {
+		return nil, fmt.Errorf("ListingService.History: %w", err)
+	}
+	return events, nil
+}
+
+// Approve одобряет объявление на модерации; note — необязательный комментарий владельцу.
+func (s *ListingService) Approve(ctx context.Context, id, note string, actor Actor) (*model.Listing, error) {
+	return s.moderate(ctx, id, model.StatusApproved, model.Moderation{Note: note, ModeratorID: actor.UserID}, actor)
+}
+
+// Reject отклоняет объявление на модерации с кодом причины и комментарием.
+func (s *ListingService) Reject(ctx context.Context, id, reason, note string, actor Actor) (*model.Listing, error) {
+	if !model.IsRejectReason(reason) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidReason, reason)
+	}
+	return s.moderate(ctx, id, model.StatusRejected, model.Moderation{Reason: reason, Note: note, ModeratorID: actor.UserID}, actor)
+}
+
+// moderate выполняет переход модерации и сохраняет решение одним UPDATE
+// в одной транзакции с событием истории.
+func (s *ListingService) moderate(ctx context.Context, id, to string, m model.Moderation, actor Actor) (*model.Listing, error) {
+	l, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkTransition(l, to, actor); err != nil {
+		return nil, err
+	}
+	action := model.EventApproved
+	if to == model.StatusRejected {
+		action = model.EventRejected
+	}
+	decision := map[string]interface{}{"status": to}
+	if m.Reason != "" {
+		decision["reason"] = m.Reason
+	}
+	if m.Note != "" {
+		decision["note"] = m.Note
+	}
+	err = s.listingRepo.InTx(ctx, s.eventRepo, func(lr *repository.ListingRepository, er *repository.ListingEventRepository) error {
+		ok, err := lr.Moderate(ctx, id, l.Status, to, m)
+		if err != nil {
+			return fmt.Errorf("ListingService.moderate: %w", err)
+		}
+		if !ok {
+			return fmt.Errorf("%w: listing %s was modified concurrently", ErrInvalidTransition, id)
+		}
+		return record(ctx, er, id, actor, action, map[string]interface{}{"status": l.Status}, decision)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.get(ctx, id)
+}
+
+// setStatus записывает статус, только если он не изменился с момента чтения:
+// иначе конкурирующий запрос мог уже перевести объявление в другой статус.
+// lr и er должны быть из одной транзакции InTx.
+func setStatus(
+	ctx context.Context,
+	lr *repository.ListingRepository,
+	er *repository.ListingEventRepository,
+	l *model.Listing,
+	to string,
+	actor Actor,
+) error {
+	from := l.Status
+	ok, err := lr.UpdateStatus(ctx, l.ID, from, to)
+	if err != nil {
+		return fmt.Errorf("ListingService.setStatus: %w", err)
+	}
+	if !ok {
+		return fmt.Errorf("%w: listing %s was modified concurrently", ErrInvalidTransition, l.ID)
+	}
+	if err := record(ctx, er, l.ID, actor, model.EventStatusChanged,
+		map[string]interface{}{"status": from}, map[string]interface{}{"status": to}); err != nil {
+		return err
+	}
+	l.Status = to
+	return nil
+}
+
+// record пишет событие в историю объявления через er — в той же транзакции,
+// что и само изменение. oldValue/newValue сериализуются в JSON; nil записывается как NULL.
+func record(
+	ctx context.Context,
+	er *repository.ListingEventRepository,
+	listingID string,
+	actor Actor,
+	action string,
+	oldValue, newValue interface{},
+) error {
+	e := &model.ListingEvent{ListingID: listingID, ActorID: actor.UserID, Action: action}
+	var err error
+	if e.OldValue, err = marshalValue(oldValue); err != nil {
+		return fmt.Errorf("ListingService.record: %w", err)
+	}
+	if e.NewValue, err = marshalValue(newValue); err != nil {
+		return fmt.Errorf("ListingService.record: %w", err)
+	}
+	if err := er.Insert(ctx, e); err != nil {
+		return fmt.Errorf("ListingService.record: %w", err)
+	}
+	return nil
+}
+
+func marshalValue(v interface{}) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+// diff сравнивает JSON-представления двух версий объявления и возвращает
+// только изменившиеся поля: старые и новые значения.
+func diff(prev, next *model.Listing) (map[string]interface{}, map[string]interface{}) {
+	prevFields, nextFields := toFields(prev), toFields(next)
+	keys := map[string]struct{}{}
+	for k := range prevFields {
+		keys[k] = struct{}{}
+	}
+	for k := range nextFields {
+		keys[k] = struct{}{}
+	}
+
+	oldOut, newOut := map[string]interface{}{}, map[string]interface{}{}
+	for k := range keys {
+		if k == "updated_at" {
+			continue
+		}
+		if pv, nv := prevFields[k], nextFields[k]; !reflect.DeepEqual(pv, nv) {
+			oldOut[k] = pv
+			newOut[k] = nv
+		}
+	}
+	return oldOut, newOut
+}
+
+func toFields(l *model.Listing) map[string]interface{} {
+	b, _ := json.Marshal(l)
+	fields := map[string]interface{}{}
+	_ = json.Unmarshal(b, &fields)
+	return fields
+}
+
+func (s *ListingService) get(ctx context.Context, id string) (*model.Listing, error) {
+	l, err := s.listingRepo.GetByID(ctx, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrListingNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ListingService.get: %w", err)
+	}
+	return l, nil
+}
+
+// checkTransition проверяет, что переход l.Status → to существует и что actor
+// имеет на него право.
+func checkTransition(l *model.Listing, to string, actor Actor) error {
+	allowed, ok := transitions[l.Status][to]
+	if !ok {
+		return fmt.Errorf("%w: %s → %s", ErrInvalidTransition, l.Status, to)
+	}
+	if actor.roles(l)&allowed == 0 {
+		return fmt.Errorf("%w: not allowed to move listing from %s to %s", ErrForbidden, l.Status, to)
+	}
+	return nil
+}
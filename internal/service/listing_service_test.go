@@ -0,0 +1,136 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"listing-service/internal/model"
+)
+
+var (
+	owner    = Actor{UserID: "owner"}
+	admin    = Actor{UserID: "admin", IsAdmin: true}
+	stranger = Actor{UserID: "stranger"}
+)
+
+func listingIn(status string) *model.Listing {
+	return &model.Listing{ID: "l1", OwnerID: "owner", Status: status}
+}
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		from, to string
+		actor    Actor
+		want     error
+	}{
+		// модерация — только админ
+		{model.StatusPending, model.StatusApproved, admin, nil},
+		{model.StatusPending, model.StatusRejected, admin, nil},
+		{model.StatusPending, model.StatusApproved, owner, ErrForbidden},
+		{model.StatusPending, model.StatusDraft, owner, nil},
+		{model.StatusPending, model.StatusDraft, admin, ErrForbidden},
+
+		{model.StatusDraft, model.StatusPending, owner, nil},
+		{model.StatusDraft, model.StatusPending, stranger, ErrForbidden},
+		{model.StatusDraft, model.StatusApproved, admin, ErrInvalidTransition},
+
+		{model.StatusApproved, model.StatusSold, owner, nil},
+		{model.StatusApproved, model.StatusArchived, admin, nil},
+		{model.StatusApproved, model.StatusExpired, admin, nil},
+		{model.StatusApproved, model.StatusExpired, owner, ErrForbidden},
+		{model.StatusApproved, model.StatusPending, owner, ErrInvalidTransition},
+
+		{model.StatusRejected, model.StatusPending, owner, nil},
+		{model.StatusRejected, model.StatusApproved, admin, ErrInvalidTransition},
+		{model.StatusArchived, model.StatusDraft, owner, nil},
+		{model.StatusExpired, model.StatusPending, owner, nil},
+		{model.StatusSold, model.StatusApproved, admin, ErrInvalidTransition},
+	}
+	for _, tt := range tests {
+		err := checkTransition(listingIn(tt.from), tt.to, tt.actor)
+		if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s → %s by %s: error = %v, want %v", tt.from, tt.to, tt.actor.UserID, err, tt.want)
+		}
+	}
+}
+
+// Каждый переход в таблице должен вести в известный статус и быть кому-то доступен.
+func TestTransitionsTable(t *testing.T) {
+	known := map[string]bool{
+		model.StatusDraft: true, model.StatusPending: true, model.StatusApproved: true,
+		model.StatusRejected: true, model.StatusArchived: true, model.StatusSold: true,
+		model.StatusExpired: true,
+	}
+	for from, targets := range transitions {
+		if !known[from] {
+			t.Errorf("unknown status %q", from)
+		}
+		for to, roles := range targets {
+			if !known[to] {
+				t.Errorf("%s → unknown status %q", from, to)
+			}
+			if roles == 0 {
+				t.Errorf("%s → %s is allowed to nobody", from, to)
+			}
+		}
+	}
+}
+
+func TestInitialStatus(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{"", model.StatusPending, false},
+		{model.StatusDraft, model.StatusDraft, false},
+		{model.StatusPending, model.StatusPending, false},
+		{model.StatusApproved, "", true},
+		{"bogus", "", true},
+	}
+	for _, tt := range tests {
+		got, err := InitialStatus(tt.in)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("InitialStatus(%q) = %q, %v; want %q, error %v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestNeedsRemoderation(t *testing.T) {
+	tests := []struct {
+		status string
+		actor  Actor
+		want   bool
+	}{
+		{model.StatusApproved, owner, true},
+		{model.StatusRejected, owner, true},
+		{model.StatusApproved, admin, false},
+		{model.StatusPending, owner, false},
+		{model.StatusDraft, owner, false},
+		{model.StatusSold, owner, false},
+	}
+	for _, tt := range tests {
+		if got := needsRemoderation(listingIn(tt.status), tt.actor); got != tt.want {
+			t.Errorf("needsRemoderation(%s, %s) = %v, want %v", tt.status, tt.actor.UserID, got, tt.want)
+		}
+	}
+}
+
+func TestCanView(t *testing.T) {
+	tests := []struct {
+		status string
+		actor  Actor
+		want   bool
+	}{
+		{model.StatusApproved, Actor{}, true},
+		{model.StatusApproved, stranger, true},
+		{model.StatusPending, Actor{}, false},
+		{model.StatusDraft, stranger, false},
+		{model.StatusRejected, owner, true},
+		{model.StatusDraft, admin, true},
+	}
+	for _, tt := range tests {
+		if got := tt.actor.CanView(listingIn(tt.status)); got != tt.want {
+			t.Errorf("CanView(%s) by %q = %v, want %v", tt.status, tt.actor.UserID, got, tt.want)
+		}
+	}
+}
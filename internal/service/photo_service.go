@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"mime/multipart"
+
+	"listing-service/internal/imaging"
+	"listing-service/internal/model"
+	"listing-service/internal/repository"
+	"listing-service/internal/storage"
+)
+
+// ErrInvalidPhotoOrder возвращается, если новый порядок галереи не перечисляет
+// каждое фото объявления ровно один раз.
+var ErrInvalidPhotoOrder = errors.New("invalid photo order")
+
+// PhotoService управляет галереями объявлений: файлы хранятся в PhotoRepository,
+// порядок и обложка — в ListingPhotoRepository. Менять галерею может владелец
+// объявления или админ.
+type PhotoService struct {
+	listingRepo *repository.ListingRepository
+	galleryRepo *repository.ListingPhotoRepository
+	photoRepo   *repository.PhotoRepository
+	maxPhotos   int         // фото в галерее одного объявления
+	limits      PhotoLimits // на одно загружаемое фото
+}
+
+// NewPhotoService создаёт PhotoService.
+func NewPhotoService(
+	lr *repository.ListingRepository,
+	gr *repository.ListingPhotoRepository,
+	pr *repository.PhotoRepository,
+	maxPhotos int,
+	limits PhotoLimits,
+) *PhotoService {
+	return &PhotoService{
+		listingRepo: lr,
+		galleryRepo: gr,
+		photoRepo:   pr,
+		maxPhotos:   maxPhotos,
+		limits:      limits,
+	}
+}
+
+// MaxPhotoBytes возвращает предел размера одного загружаемого фото.
+func (s *PhotoService) MaxPhotoBytes() int64 {
+	return s.limits.MaxBytes
+}
+
+// ListingPhotos возвращает галерею объявления, которое видно actor, в порядке показа.
+func (s *PhotoService) ListingPhotos(ctx context.Context, listingID string, actor Actor) ([]model.ListingPhoto, error) {
+	if _, err := findVisible(ctx, s.listingRepo, listingID, actor); err != nil {
+		return nil, fmt.Errorf("PhotoService.ListingPhotos: %w", err)
+	}
+	return s.gallery(ctx, listingID)
+}
+
+// gallery возвращает галерею объявления без проверки видимости.
+func (s *PhotoService) gallery(ctx context.Context, listingID string) ([]model.ListingPhoto, error) {
+	photos, err := s.galleryRepo.FindByListing(ctx, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("PhotoService.gallery: %w", err)
+	}
+	return photos, nil
+}
+
+// AddListingPhoto загружает файл и добавляет его в конец галереи. cover делает
+// фото обложкой; первое фото галереи становится обложкой всегда.
+func (s *PhotoService) AddListingPhoto(
+	ctx context.Context,
+	listingID string,
+	actor Actor,
+	file multipart.File,
+	filename string,
+	cover bool,
+) (*model.ListingPhoto, error) {
+	if _, err := s.manage(ctx, listingID, actor); err != nil {
+		return nil, fmt.Errorf("PhotoService.AddListingPhoto: %w", err)
+	}
+
+	fileID, variants, err := uploadWithVariants(ctx, s.photoRepo, file, filename, s.limits)
+	if err != nil {
+		return nil, fmt.Errorf("PhotoService.AddListingPhoto: %w", err)
+	}
+	photo := &model.ListingPhoto{ListingID: listingID, FileID: fileID, PhotoVariants: variants}
+	if err := s.galleryRepo.Add(ctx, photo, s.maxPhotos, cover); err != nil {
+		deleteFiles(ctx, s.photoRepo, photo.AllFileIDs(fileID)...)
+		switch {
+		case errors.Is(err, repository.ErrGalleryFull):
+			return nil, fmt.Errorf("PhotoService.AddListingPhoto: %w (max %d)", ErrTooManyPhotos, s.maxPhotos)
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, fmt.Errorf("PhotoService.AddListingPhoto: %w", ErrListingNotFound)
+		}
+		return nil, fmt.Errorf("PhotoService.AddListingPhoto: %w", err)
+	}
+	return photo, nil
+}
+
+// DeleteListingPhoto удаляет фото из галереи вместе с файлами оригинала и вариантов.
+func (s *PhotoService) DeleteListingPhoto(ctx context.Context, listingID, photoID string, actor Actor) error {
+	if _, err := s.manage(ctx, listingID, actor); err != nil {
+		return fmt.Errorf("PhotoService.DeleteListingPhoto: %w", err)
+	}
+	photo, err := s.galleryRepo.Delete(ctx, listingID, photoID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("PhotoService.DeleteListingPhoto: %w", ErrPhotoNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("PhotoService.DeleteListingPhoto: %w", err)
+	}
+	deleteFiles(ctx, s.photoRepo, photo.AllFileIDs(photo.FileID)...)
+	return nil
+}
+
+// ReorderListingPhotos задаёт порядок показа галереи.
+func (s *PhotoService) ReorderListingPhotos(ctx context.Context, listingID string, photoIDs []string, actor Actor) ([]model.ListingPhoto, error) {
+	if _, err := s.manage(ctx, listingID, actor); err != nil {
+		return nil, fmt.Errorf("PhotoService.ReorderListingPhotos: %w", err)
+	}
+	err := s.galleryRepo.Reorder(ctx, listingID, photoIDs)
+	if errors.Is(err, repository.ErrPhotoOrderMismatch) {
+		return nil, fmt.Errorf("PhotoService.ReorderListingPhotos: %w", ErrInvalidPhotoOrder)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("PhotoService.ReorderListingPhotos: %w", err)
+	}
+	return s.gallery(ctx, listingID)
+}
+
+// SetCover делает фото обложкой объявления.
+func (s *PhotoService) SetCover(ctx context.Context, listingID, photoID string, actor Actor) ([]model.ListingPhoto, error) {
+	if _, err := s.manage(ctx, listingID, actor); err != nil {
+		return nil, fmt.Errorf("PhotoService.SetCover: %w", err)
+	}
+	err := s.galleryRepo.SetCover(ctx, listingID, photoID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("PhotoService.SetCover: %w", ErrPhotoNotFound)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("PhotoService.SetCover: %w", err)
+	}
+	return s.gallery(ctx, listingID)
+}
+
+// DownloadListingPhoto открывает фото из галереи видимого actor объявления
+// в варианте size (оригинал, если варианта нет). Файл закрывает вызывающий.
+func (s *PhotoService) DownloadListingPhoto(
+	ctx context.Context,
+	listingID, photoID string,
+	actor Actor,
+	size imaging.Size,
+) (*storage.Blob, error) {
+	if _, err := findVisible(ctx, s.listingRepo, listingID, actor); err != nil {
+		return nil, fmt.Errorf("PhotoService.DownloadListingPhoto: %w", err)
+	}
+	photos, err := s.galleryRepo.FindByListing(ctx, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("PhotoService.DownloadListingPhoto: %w", err)
+	}
+	for _, p := range photos {
+		if p.ID == photoID {
+			f, err := s.photoRepo.OpenPhoto(ctx, p.VariantFileID(string(size), p.FileID))
+			if err != nil {
+				return nil, fmt.Errorf("PhotoService.DownloadListingPhoto: %w", err)
+			}
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("PhotoService.DownloadListingPhoto: %w", ErrPhotoNotFound)
+}
+
+// DownloadCover открывает обложку видимого actor объявления в варианте size.
+// Если строки обложки в галерее нет, открывается оригинал из photo_file_id.
+func (s *PhotoService) DownloadCover(ctx context.Context, listingID string, actor Actor, size imaging.Size) (*storage.Blob, error) {
+	l, err := findVisible(ctx, s.listingRepo, listingID, actor)
+	if err != nil {
+		return nil, fmt.Errorf("PhotoService.DownloadCover: %w", err)
+	}
+	if l.PhotoFileID == "" {
+		return nil, fmt.Errorf("PhotoService.DownloadCover: %w", ErrPhotoNotFound)
+	}
+	photos, err := s.galleryRepo.FindByListing(ctx, listingID)
+	if err != nil {
+		return nil, fmt.Errorf("PhotoService.DownloadCover: %w", err)
+	}
+	fileID := l.PhotoFileID
+	for _, p := range photos {
+		if p.IsCover {
+			fileID = p.VariantFileID(string(size), p.FileID)
+			break
+		}
+	}
+	f, err := s.photoRepo.OpenPhoto(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("PhotoService.DownloadCover: %w", err)
+	}
+	return f, nil
+}
+
+// manage загружает объявление и проверяет, что actor может менять его галерею.
+func (s *PhotoService) manage(ctx context.Context, listingID string, actor Actor) (*model.Listing, error) {
+	l, err := s.listingRepo.GetByID(ctx, listingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrListingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !actor.CanManage(l) {
+		return nil, fmt.Errorf("%w: only the owner can change photos", ErrForbidden)
+	}
+	return l, nil
+}
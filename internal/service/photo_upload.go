@@ -0,0 +1,106 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"listing-service/internal/imaging"
+	"listing-service/internal/model"
+	"listing-service/internal/repository"
+	"listing-service/internal/storage"
+)
+
+const (
+	// DefaultPhotoMaxBytes — предел размера загружаемого фото, если PHOTO_MAX_BYTES не задан.
+	DefaultPhotoMaxBytes = 10 << 20
+	// DefaultPhotoMaxPixels — предел ширины × высоты фото, если PHOTO_MAX_PIXELS не задан.
+	// Декодированное изображение занимает 4 байта на пиксель, то есть до 160 МБ.
+	DefaultPhotoMaxPixels = 40_000_000
+)
+
+// PhotoLimits — ограничения на одно загружаемое фото.
+type PhotoLimits struct {
+	MaxBytes  int64 // размер файла
+	MaxPixels int   // ширина × высота, проверяется до декодирования
+}
+
+var (
+	// ErrPhotoTooLarge возвращается, если фото больше PHOTO_MAX_BYTES или PHOTO_MAX_PIXELS.
+	ErrPhotoTooLarge = errors.New("photo is too large")
+	// ErrUnsupportedPhoto возвращается, если файл не JPEG/PNG/WebP или битый.
+	ErrUnsupportedPhoto = errors.New("unsupported photo")
+)
+
+// uploadWithVariants проверяет загруженный файл и сохраняет оригинал и его
+// уменьшенные копии. Тип определяется по содержимому; файлы больше limits,
+// не JPEG/PNG/WebP и битые отклоняются. При ошибке уже загруженные файлы удаляются.
+func uploadWithVariants(
+	ctx context.Context,
+	pr *repository.PhotoRepository,
+	file io.Reader,
+	filename string,
+	limits PhotoLimits,
+) (string, model.PhotoVariants, error) {
+	var v model.PhotoVariants
+	data, err := io.ReadAll(io.LimitReader(file, limits.MaxBytes+1))
+	if err != nil {
+		return "", v, fmt.Errorf("read: %w", err)
+	}
+	if int64(len(data)) > limits.MaxBytes {
+		return "", v, fmt.Errorf("%w (max %d bytes)", ErrPhotoTooLarge, limits.MaxBytes)
+	}
+	src, contentType, err := imaging.Decode(data, limits.MaxPixels)
+	if errors.Is(err, imaging.ErrTooManyPixels) {
+		return "", v, fmt.Errorf("%w: %v", ErrPhotoTooLarge, err)
+	}
+	if err != nil {
+		return "", v, fmt.Errorf("%w: %v", ErrUnsupportedPhoto, err)
+	}
+	variants, err := imaging.Variants(src, contentType)
+	if err != nil {
+		return "", v, err
+	}
+
+	filename = filepath.Base(filename)
+	meta := storage.Meta{ContentType: contentType, Filename: filename}
+	fileID, err := pr.UploadPhoto(ctx, bytes.NewReader(data), int64(len(data)), meta)
+	if err != nil {
+		return "", v, err
+	}
+
+	targets := map[imaging.Size]**string{
+		imaging.Thumb:  &v.ThumbFileID,
+		imaging.Medium: &v.MediumFileID,
+		imaging.Large:  &v.LargeFileID,
+	}
+	base := strings.TrimSuffix(filename, filepath.Ext(filename))
+	for size, img := range variants {
+		name := fmt.Sprintf("%s_%s%s", base, size, imaging.Extension(img.ContentType))
+		meta := storage.Meta{ContentType: img.ContentType, Filename: name}
+		id, err := pr.UploadPhoto(ctx, bytes.NewReader(img.Data), int64(len(img.Data)), meta)
+		if err != nil {
+			deleteFiles(ctx, pr, v.AllFileIDs(fileID)...)
+			return "", model.PhotoVariants{}, fmt.Errorf("variant %s: %w", size, err)
+		}
+		*targets[size] = &id
+	}
+	return fileID, v, nil
+}
+
+// deleteFiles удаляет файлы фото, строки которых уже удалены или так и не были
+// записаны. Ошибка лишь оставляет осиротевший файл, поэтому она логируется,
+// а не возвращается.
+func deleteFiles(ctx context.Context, pr *repository.PhotoRepository, fileIDs ...string) {
+	ctx = context.WithoutCancel(ctx) // файлы удаляются, даже если клиент уже отключился
+	for _, id := range fileIDs {
+		if err := pr.DeletePhoto(ctx, id); err != nil {
+			log.Printf("[deleteFiles] failed to delete photo file %s: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"listing-service/internal/model"
+	"listing-service/internal/repository"
+)
+
+// purgeBatchSize — сколько объявлений очищается за один проход.
+const purgeBatchSize = 100
+
+// RetentionJob окончательно удаляет объявления, мягко удалённые больше
+// Retention назад, вместе с их отзывами, галереей и файлами фото.
+type RetentionJob struct {
+	listingRepo *repository.ListingRepository
+	eventRepo   *repository.ListingEventRepository
+	photoRepo   *repository.PhotoRepository
+	Retention   time.Duration
+	Interval    time.Duration
+}
+
+// NewRetentionJob создаёт RetentionJob.
+func NewRetentionJob(
+	lr *repository.ListingRepository,
+	er *repository.ListingEventRepository,
+	pr *repository.PhotoRepository,
+	retention, interval time.Duration,
+) *RetentionJob {
+	return &RetentionJob{
+		listingRepo: lr,
+		eventRepo:   er,
+		photoRepo:   pr,
+		Retention:   retention,
+		Interval:    interval,
+	}
+}
+
+// Run запускает очистку сразу и затем каждые Interval, пока ctx не отменён.
+func (j *RetentionJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.Interval)
+	defer ticker.Stop()
+	for {
+		n, err := j.PurgeOnce(ctx)
+		if n > 0 {
+			log.Printf("[RetentionJob] purged %d listings", n)
+		}
+		if err != nil {
+			log.Printf("[RetentionJob] purge failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeOnce очищает все просроченные объявления и возвращает их число.
+// Фото удаляются до строки в Postgres: если удаление фото упадёт,
+// объявление останется и будет очищено на следующем проходе.
+// Ошибка одного объявления не останавливает очистку остальных: оно
+// пропускается до конца прохода, а ошибки возвращаются вместе.
+func (j *RetentionJob) PurgeOnce(ctx context.Context) (int, error) {
+	before := time.Now().Add(-j.Retention)
+	purged := 0
+	var failed []string
+	var errs []error
+	for {
+		list, err := j.listingRepo.FindPurgeable(ctx, before, failed, purgeBatchSize)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("RetentionJob.PurgeOnce: %w", err))
+			return purged, errors.Join(errs...)
+		}
+		for _, l := range list {
+			if err := j.purge(ctx, &l); err != nil {
+				log.Printf("[RetentionJob] skipping listing %s: %v", l.ID, err)
+				failed = append(failed, l.ID)
+				errs = append(errs, err)
+				continue
+			}
+			purged++
+		}
+		if len(list) < purgeBatchSize {
+			return purged, errors.Join(errs...)
+		}
+	}
+}
+
+func (j *RetentionJob) purge(ctx context.Context, l *model.Listing) error {
+	photos, err := j.listingRepo.PhotoFileIDs(ctx, l.ID)
+	if err != nil {
+		return fmt.Errorf("RetentionJob.purge %s: %w", l.ID, err)
+	}
+	reviewPhotos, err := j.listingRepo.ReviewPhotoFileIDs(ctx, l.ID)
+	if err != nil {
+		return fmt.Errorf("RetentionJob.purge %s: %w", l.ID, err)
+	}
+	for _, fileID := range append(photos, reviewPhotos...) {
+		if err := j.photoRepo.DeletePhoto(ctx, fileID); err != nil {
+			return fmt.Errorf("RetentionJob.purge %s photo: %w", l.ID, err)
+		}
+	}
+	err = j.listingRepo.InTx(ctx, j.eventRepo, func(lr *repository.ListingRepository, er *repository.ListingEventRepository) error {
+		if err := lr.Purge(ctx, l.ID); err != nil {
+			return err
+		}
+		e := &model.ListingEvent{ListingID: l.ID, ActorID: "system", Action: model.EventPurged}
+		return er.Insert(ctx, e)
+	})
+	if err != nil {
+		return fmt.Errorf("RetentionJob.purge %s: %w", l.ID, err)
+	}
+	return nil
+}
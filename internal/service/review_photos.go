@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime/multipart"
+
+	"listing-service/internal/imaging"
+	"listing-service/internal/model"
+	"listing-service/internal/repository"
+	"listing-service/internal/storage"
+)
+
+var (
+	// ErrTooManyPhotos is returned when a review or a listing gallery already has the maximum number of photos.
+	ErrTooManyPhotos = errors.New("too many photos")
+	// ErrPhotoNotFound is returned when the photo does not exist on the given review or listing.
+	ErrPhotoNotFound = errors.New("photo not found")
+)
+
+// AddReviewPhoto validates an uploaded image, stores it in the photo repository
+// and attaches it to the actor's own review, up to the configured maximum per review.
+func (s *ReviewService) AddReviewPhoto(
+	ctx context.Context,
+	listingID, reviewID string,
+	actor Actor,
+	file multipart.File,
+	filename string,
+) (*model.ReviewPhoto, error) {
+	rev, err := s.findManagedReview(ctx, listingID, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("ReviewService.AddReviewPhoto: %w", err)
+	}
+	if rev.UserID != actor.UserID {
+		return nil, fmt.Errorf("ReviewService.AddReviewPhoto: %w: only the author can add photos", ErrForbidden)
+	}
+
+	// Cheap check before the upload; AddPhoto re-checks under a row lock.
+	if len(rev.Photos) >= s.maxPhotos {
+		return nil, fmt.Errorf("ReviewService.AddReviewPhoto: %w (max %d)", ErrTooManyPhotos, s.maxPhotos)
+	}
+
+	fileID, variants, err := uploadWithVariants(ctx, s.photoRepo, file, filename, s.limits)
+	if err != nil {
+		return nil, fmt.Errorf("ReviewService.AddReviewPhoto: %w", err)
+	}
+	photo := &model.ReviewPhoto{ReviewID: reviewID, FileID: fileID, PhotoVariants: variants}
+	if err := s.reviewRepo.AddPhoto(ctx, photo, s.maxPhotos); err != nil {
+		deleteFiles(ctx, s.photoRepo, photo.AllFileIDs(fileID)...)
+		if errors.Is(err, repository.ErrTooManyPhotos) {
+			return nil, fmt.Errorf("ReviewService.AddReviewPhoto: %w (max %d)", ErrTooManyPhotos, s.maxPhotos)
+		}
+		return nil, fmt.Errorf("ReviewService.AddReviewPhoto: %w", err)
+	}
+	return photo, nil
+}
+
+// DeleteReviewPhoto removes a photo from a review (by its author or an admin).
+func (s *ReviewService) DeleteReviewPhoto(ctx context.Context, listingID, reviewID, photoID string, actor Actor) error {
+	rev, err := s.findManagedReview(ctx, listingID, reviewID)
+	if err != nil {
+		return fmt.Errorf("ReviewService.DeleteReviewPhoto: %w", err)
+	}
+	photo, err := reviewPhoto(rev, photoID)
+	if err != nil {
+		return fmt.Errorf("ReviewService.DeleteReviewPhoto: %w", err)
+	}
+	if rev.UserID != actor.UserID && !actor.IsAdmin {
+		return fmt.Errorf("ReviewService.DeleteReviewPhoto: %w: only the author can delete photos", ErrForbidden)
+	}
+
+	if err := s.reviewRepo.DeletePhoto(ctx, photoID); err != nil {
+		return fmt.Errorf("ReviewService.DeleteReviewPhoto: %w", err)
+	}
+	deleteFiles(ctx, s.photoRepo, photo.AllFileIDs(photo.FileID)...)
+	return nil
+}
+
+// DownloadReviewPhoto opens a photo on a visible review of a listing the actor
+// can see, in the requested size, falling back to the original when that
+// variant does not exist. The caller closes the returned file.
+func (s *ReviewService) DownloadReviewPhoto(
+	ctx context.Context,
+	listingID, reviewID, photoID string,
+	actor Actor,
+	size imaging.Size,
+) (*storage.Blob, error) {
+	if _, err := findVisible(ctx, s.listingRepo, listingID, actor); err != nil {
+		return nil, fmt.Errorf("ReviewService.DownloadReviewPhoto: %w", err)
+	}
+	photo, err := s.findPhoto(ctx, listingID, reviewID, photoID)
+	if err != nil {
+		return nil, fmt.Errorf("ReviewService.DownloadReviewPhoto: %w", err)
+	}
+	f, err := s.photoRepo.OpenPhoto(ctx, photo.VariantFileID(string(size), photo.FileID))
+	if err != nil {
+		return nil, fmt.Errorf("ReviewService.DownloadReviewPhoto: %w", err)
+	}
+	return f, nil
+}
+
+// attachPhotos fills Photos on every review of a page with a single query.
+func (s *ReviewService) attachPhotos(ctx context.Context, reviews []model.Review) error {
+	ids := make([]string, len(reviews))
+	for i := range reviews {
+		ids[i] = reviews[i].ID
+	}
+	photos, err := s.reviewRepo.FindPhotos(ctx, ids)
+	if err != nil {
+		return err
+	}
+	for i := range reviews {
+		reviews[i].Photos = photos[reviews[i].ID]
+	}
+	return nil
+}
+
+// findPhoto loads a photo and checks that it belongs to a visible review on listingID.
+func (s *ReviewService) findPhoto(ctx context.Context, listingID, reviewID, photoID string) (*model.ReviewPhoto, error) {
+	rev, err := s.findReview(ctx, listingID, reviewID)
+	if err != nil {
+		return nil, err
+	}
+	return reviewPhoto(rev, photoID)
+}
+
+// reviewPhoto returns the photo photoID of a loaded review.
+func reviewPhoto(rev *model.Review, photoID string) (*model.ReviewPhoto, error) {
+	for i := range rev.Photos {
+		if rev.Photos[i].ID == photoID {
+			return &rev.Photos[i], nil
+		}
+	}
+	return nil, ErrPhotoNotFound
+}
@@ -2,32 +2,69 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
 
 	"listing-service/internal/model"
+	"listing-service/internal/pagination"
 	"listing-service/internal/repository"
 )
 
+var (
+	// ErrOwnListingReview is returned when an owner tries to review their own listing.
+	ErrOwnListingReview = errors.New("owners cannot review their own listing")
+	// ErrDuplicateReview is returned when the user already reviewed the listing.
+	ErrDuplicateReview = errors.New("you have already reviewed this listing")
+	// ErrReviewNotFound is returned when the review does not exist on the given listing.
+	ErrReviewNotFound = errors.New("review not found")
+	// ErrReplyNotFound is returned when deleting a reply that does not exist.
+	ErrReplyNotFound = errors.New("reply not found")
+	// ErrOwnReviewVote is returned when an author marks their own review as helpful.
+	ErrOwnReviewVote = errors.New("you cannot vote for your own review")
+	// ErrInvalidReportReason is returned when a report has an unknown reason code.
+	ErrInvalidReportReason = errors.New("invalid report reason")
+	// ErrDuplicateReport is returned when the user already has an open report on the review.
+	ErrDuplicateReport = errors.New("you have already reported this review")
+)
+
 // ReviewService contains business logic for reviews.
 type ReviewService struct {
 	reviewRepo  *repository.ReviewRepository
 	listingRepo *repository.ListingRepository
+	photoRepo   *repository.PhotoRepository
+	maxPhotos   int         // photos allowed per review
+	limits      PhotoLimits // limits of a single photo upload
 }
 
 // NewReviewService constructs a ReviewService with its required repositories.
+// maxPhotos caps the number of photos attached to a single review and limits
+// the size of each of them.
 func NewReviewService(
 	rr *repository.ReviewRepository,
 	lr *repository.ListingRepository,
+	pr *repository.PhotoRepository,
+	maxPhotos int,
+	limits PhotoLimits,
 ) *ReviewService {
 	return &ReviewService{
 		reviewRepo:  rr,
 		listingRepo: lr,
+		photoRepo:   pr,
+		maxPhotos:   maxPhotos,
+		limits:      limits,
 	}
 }
 
-// CreateReview checks that the listing (by its string ID) exists, inserts a new review,
-// recalculates the average rating for that listing, and returns the newly created Review.
-// Note: listingID is now a string (e.g. a UUID or text column).
+// MaxPhotoBytes returns the size limit of a single photo upload.
+func (s *ReviewService) MaxPhotoBytes() int64 {
+	return s.limits.MaxBytes
+}
+
+// CreateReview checks that the listing (by its string ID) exists, is approved and
+// is not owned by the author, inserts a new review together with the listing's rating
+// aggregates, and returns the newly created Review.
+// userID is the author taken from the verified token, never from the request body.
 func (s *ReviewService) CreateReview(
 	ctx context.Context,
 	listingID string, // ← changed from int64 to string
@@ -35,13 +72,20 @@ func (s *ReviewService) CreateReview(
 	rating int,
 	comment string,
 ) (*model.Review, error) {
-	// 1) Verify that the listing exists.
-	exists, err := s.listingRepo.Exists(ctx, listingID)
+	// 1) Verify that the listing exists and the author is not its owner.
+	//    Unpublished listings are hidden from reviewers, so they are not found either.
+	listing, err := s.listingRepo.GetByID(ctx, listingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("ReviewService.CreateReview: listing %s: %w", listingID, ErrListingNotFound)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("ReviewService.CreateReview: checking listing exists: %w", err)
+		return nil, fmt.Errorf("ReviewService.CreateReview: loading listing: %w", err)
 	}
-	if !exists {
-		return nil, fmt.Errorf("ReviewService.CreateReview: listing %s not found", listingID)
+	if listing.Status != model.StatusApproved {
+		return nil, fmt.Errorf("ReviewService.CreateReview: listing %s is %s: %w", listingID, listing.Status, ErrListingNotFound)
+	}
+	if listing.OwnerID == userID {
+		return nil, fmt.Errorf("ReviewService.CreateReview: %w", ErrOwnListingReview)
 	}
 
 	// 2) Build the Review model. ID and CreatedAt will be set by the repository.
@@ -52,41 +96,285 @@ func (s *ReviewService) CreateReview(
 		Comment:   comment,
 	}
 
-	// 3) Insert into the database.
-	//    Insert returns the new review.ID as a string.
+	// 3) Insert into the database; the listing's rating aggregates are
+	//    updated in the same transaction.
 	newID, err := s.reviewRepo.Insert(ctx, rev)
+	if errors.Is(err, repository.ErrDuplicateReview) {
+		return nil, fmt.Errorf("ReviewService.CreateReview: %w", ErrDuplicateReview)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("ReviewService.CreateReview: insert: %w", err)
 	}
 	rev.ID = newID
 
-	// 4) Recalculate the average_rating for this listing.
-	if err := s.reviewRepo.RecalcAverage(ctx, listingID); err != nil {
-		return nil, fmt.Errorf("ReviewService.CreateReview: recalc average: %w", err)
-	}
-
 	return rev, nil
 }
 
-// GetReviews fetches all reviews for the given listing (by string ID),
-// sorted by created_at DESC. Returns a slice of model.Review.
+// GetReviews fetches one page of reviews for the given listing (by string ID),
+// filtered and sorted as requested (newest first by default). Reviews of an
+// unpublished listing are only shown to its owner and admins.
 func (s *ReviewService) GetReviews(
 	ctx context.Context,
 	listingID string, // ← changed from int64 to string
-) ([]model.Review, error) {
-	// 1) Verify that the listing exists.
-	exists, err := s.listingRepo.Exists(ctx, listingID)
-	if err != nil {
-		return nil, fmt.Errorf("ReviewService.GetReviews: checking listing exists: %w", err)
-	}
-	if !exists {
-		return nil, fmt.Errorf("ReviewService.GetReviews: listing %s not found", listingID)
+	actor Actor,
+	filter repository.ReviewFilter,
+	page pagination.Params,
+) (*pagination.Page[model.Review], error) {
+	// 1) Verify that the listing exists and the actor may see it.
+	if _, err := findVisible(ctx, s.listingRepo, listingID, actor); err != nil {
+		return nil, fmt.Errorf("ReviewService.GetReviews: listing %s: %w", listingID, err)
 	}
 
 	// 2) Delegate to the repository to load reviews.
-	reviews, err := s.reviewRepo.FindByListing(ctx, listingID)
+	reviews, err := s.reviewRepo.FindByListing(ctx, listingID, filter, page)
 	if err != nil {
 		return nil, fmt.Errorf("ReviewService.GetReviews: find by listing: %w", err)
 	}
+	if err := s.attachPhotos(ctx, reviews.Items); err != nil {
+		return nil, fmt.Errorf("ReviewService.GetReviews: photos: %w", err)
+	}
 	return reviews, nil
 }
+
+// UpdateReview changes the rating and comment of the actor's own review; the
+// listing's rating aggregates follow in the same transaction.
+func (s *ReviewService) UpdateReview(
+	ctx context.Context,
+	listingID, reviewID string,
+	actor Actor,
+	rating int,
+	comment string,
+) (*model.Review, error) {
+	rev, err := s.findManagedReview(ctx, listingID, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("ReviewService.UpdateReview: %w", err)
+	}
+	if rev.UserID != actor.UserID {
+		return nil, fmt.Errorf("ReviewService.UpdateReview: %w: only the author can edit a review", ErrForbidden)
+	}
+
+	rev.Rating = rating
+	rev.Comment = comment
+	if err := s.reviewRepo.Update(ctx, rev); err != nil {
+		return nil, fmt.Errorf("ReviewService.UpdateReview: %w", err)
+	}
+	if rev.VotedByMe, err = s.reviewRepo.HasVoted(ctx, reviewID, actor.UserID); err != nil {
+		return nil, fmt.Errorf("ReviewService.UpdateReview: %w", err)
+	}
+	return rev, nil
+}
+
+// DeleteReview removes a review (by its author or an admin) together with its
+// photos and its contribution to the listing's rating aggregates.
+func (s *ReviewService) DeleteReview(ctx context.Context, listingID, reviewID string, actor Actor) error {
+	rev, err := s.findManagedReview(ctx, listingID, reviewID)
+	if err != nil {
+		return fmt.Errorf("ReviewService.DeleteReview: %w", err)
+	}
+	if rev.UserID != actor.UserID && !actor.IsAdmin {
+		return fmt.Errorf("ReviewService.DeleteReview: %w: only the author can delete a review", ErrForbidden)
+	}
+
+	if err := s.reviewRepo.Delete(ctx, reviewID); err != nil {
+		return fmt.Errorf("ReviewService.DeleteReview: %w", err)
+	}
+	for _, p := range rev.Photos {
+		deleteFiles(ctx, s.photoRepo, p.AllFileIDs(p.FileID)...)
+	}
+	return nil
+}
+
+// VoteHelpful marks a review as helpful on behalf of actor (one vote per user)
+// and returns the review with its updated helpful count.
+func (s *ReviewService) VoteHelpful(ctx context.Context, listingID, reviewID string, actor Actor) (*model.Review, error) {
+	rev, err := s.findReview(ctx, listingID, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("ReviewService.VoteHelpful: %w", err)
+	}
+	if rev.UserID == actor.UserID {
+		return nil, fmt.Errorf("ReviewService.VoteHelpful: %w", ErrOwnReviewVote)
+	}
+	if rev.HelpfulCount, err = s.reviewRepo.AddVote(ctx, reviewID, actor.UserID); err != nil {
+		return nil, fmt.Errorf("ReviewService.VoteHelpful: %w", err)
+	}
+	rev.VotedByMe = true
+	return rev, nil
+}
+
+// UnvoteHelpful withdraws actor's helpful vote and returns the review with its
+// updated helpful count.
+func (s *ReviewService) UnvoteHelpful(ctx context.Context, listingID, reviewID string, actor Actor) (*model.Review, error) {
+	rev, err := s.findReview(ctx, listingID, reviewID)
+	if err != nil {
+		return nil, fmt.Errorf("ReviewService.UnvoteHelpful: %w", err)
+	}
+	if rev.HelpfulCount, err = s.reviewRepo.RemoveVote(ctx, reviewID, actor.UserID); err != nil {
+		return nil, fmt.Errorf("ReviewService.UnvoteHelpful: %w", err)
+	}
+	rev.VotedByMe = false
+	return rev, nil
+}
+
+// ReportReview files actor's abuse report on a review. reason must be one of
+// the model.ReportReason* codes; note is optional.
+func (s *ReviewService) ReportReview(ctx context.Context, listingID, reviewID string, actor Actor, reason, note string) (*model.ReviewReport, error) {
+	if !model.IsReportReason(reason) {
+		return nil, fmt.Errorf("ReviewService.ReportReview: %w: %q", ErrInvalidReportReason, reason)
+	}
+	if _, err := s.findReview(ctx, listingID, reviewID); err != nil {
+		return nil, fmt.Errorf("ReviewService.ReportReview: %w", err)
+	}
+
+	report := &model.ReviewReport{
+		ReviewID:   reviewID,
+		ReporterID: actor.UserID,
+		Reason:     reason,
+	}
+	if note != "" {
+		report.Note = &note
+	}
+	err := s.reviewRepo.InsertReport(ctx, report)
+	if errors.Is(err, repository.ErrDuplicateReport) {
+		return nil, fmt.Errorf("ReviewService.ReportReview: %w", ErrDuplicateReport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ReviewService.ReportReview: %w", err)
+	}
+	return report, nil
+}
+
+// ReportedReviews returns one page of the admin queue of reviews with open reports.
+func (s *ReviewService) ReportedReviews(ctx context.Context, page pagination.Params) (*pagination.Page[model.ReportedReview], error) {
+	reviews, err := s.reviewRepo.FindReported(ctx, page)
+	if err != nil {
+		return nil, fmt.Errorf("ReviewService.ReportedReviews: %w", err)
+	}
+	ids := make([]string, len(reviews.Items))
+	for i := range reviews.Items {
+		ids[i] = reviews.Items[i].ID
+	}
+	photos, err := s.reviewRepo.FindPhotos(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("ReviewService.ReportedReviews: photos: %w", err)
+	}
+	for i := range reviews.Items {
+		reviews.Items[i].Photos = photos[reviews.Items[i].ID]
+	}
+	return reviews, nil
+}
+
+// HideReview takes a review down (admin only): it disappears from the listing's
+// reviews and rating aggregates, and its open reports are resolved.
+func (s *ReviewService) HideReview(ctx context.Context, reviewID string, actor Actor) error {
+	return s.setHidden(ctx, reviewID, actor, true)
+}
+
+// RestoreReview makes a review visible again (admin only) and resolves its open
+// reports; for a visible review this simply dismisses the reports.
+func (s *ReviewService) RestoreReview(ctx context.Context, reviewID string, actor Actor) error {
+	return s.setHidden(ctx, reviewID, actor, false)
+}
+
+// setHidden is the shared body of HideReview and RestoreReview.
+func (s *ReviewService) setHidden(ctx context.Context, reviewID string, actor Actor, hidden bool) error {
+	if !actor.IsAdmin {
+		return fmt.Errorf("%w: only admins can moderate reviews", ErrForbidden)
+	}
+	_, err := s.reviewRepo.FindByID(ctx, reviewID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrReviewNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.reviewRepo.SetHidden(ctx, reviewID, actor.UserID, hidden); err != nil {
+		return fmt.Errorf("ReviewService.setHidden: %w", err)
+	}
+	return nil
+}
+
+// SetReply creates or edits the listing owner's public reply to a review.
+func (s *ReviewService) SetReply(ctx context.Context, listingID, reviewID string, actor Actor, text string) (*model.Review, error) {
+	rev, err := s.findOwnedReview(ctx, listingID, reviewID, actor)
+	if err != nil {
+		return nil, fmt.Errorf("ReviewService.SetReply: %w", err)
+	}
+	if err := s.reviewRepo.SetReply(ctx, rev, text); err != nil {
+		return nil, fmt.Errorf("ReviewService.SetReply: %w", err)
+	}
+	if rev.VotedByMe, err = s.reviewRepo.HasVoted(ctx, reviewID, actor.UserID); err != nil {
+		return nil, fmt.Errorf("ReviewService.SetReply: %w", err)
+	}
+	return rev, nil
+}
+
+// DeleteReply removes the listing owner's reply from a review.
+func (s *ReviewService) DeleteReply(ctx context.Context, listingID, reviewID string, actor Actor) error {
+	rev, err := s.findOwnedReview(ctx, listingID, reviewID, actor)
+	if err != nil {
+		return fmt.Errorf("ReviewService.DeleteReply: %w", err)
+	}
+	if rev.ReplyText == nil {
+		return fmt.Errorf("ReviewService.DeleteReply: %w", ErrReplyNotFound)
+	}
+	if err := s.reviewRepo.DeleteReply(ctx, reviewID); err != nil {
+		return fmt.Errorf("ReviewService.DeleteReply: %w", err)
+	}
+	return nil
+}
+
+// findOwnedReview loads a review on listingID and checks that actor owns the listing.
+func (s *ReviewService) findOwnedReview(ctx context.Context, listingID, reviewID string, actor Actor) (*model.Review, error) {
+	listing, err := s.listingRepo.GetByID(ctx, listingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrListingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if actor.UserID == "" || listing.OwnerID != actor.UserID {
+		return nil, fmt.Errorf("%w: only the listing owner can reply to reviews", ErrForbidden)
+	}
+	return s.findReview(ctx, listingID, reviewID)
+}
+
+// findReview loads a visible review with its photos and checks that it belongs
+// to listingID. Hidden reviews are reported as not found, and so are all reviews
+// of a soft-deleted listing.
+func (s *ReviewService) findReview(ctx context.Context, listingID, reviewID string) (*model.Review, error) {
+	return s.loadReview(ctx, listingID, reviewID, false)
+}
+
+// findManagedReview is findReview for changes made by the review's author or an
+// admin: a hidden review is loaded too, so it can still be edited or deleted.
+// Only the public read paths and other users' actions must not see it.
+func (s *ReviewService) findManagedReview(ctx context.Context, listingID, reviewID string) (*model.Review, error) {
+	return s.loadReview(ctx, listingID, reviewID, true)
+}
+
+// loadReview implements findReview and findManagedReview.
+func (s *ReviewService) loadReview(ctx context.Context, listingID, reviewID string, withHidden bool) (*model.Review, error) {
+	exists, err := s.listingRepo.Exists(ctx, listingID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("listing %s: %w", listingID, ErrListingNotFound)
+	}
+	rev, err := s.reviewRepo.FindByID(ctx, reviewID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrReviewNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rev.ListingID != listingID || (rev.HiddenAt != nil && !withHidden) {
+		return nil, ErrReviewNotFound
+	}
+	photos, err := s.reviewRepo.FindPhotos(ctx, []string{rev.ID})
+	if err != nil {
+		return nil, err
+	}
+	rev.Photos = photos[rev.ID]
+	return rev, nil
+}
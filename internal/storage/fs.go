@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FS хранит файлы в локальной папке — для разработки без MongoDB и S3.
+// Файл id лежит в Root/<первые 2 символа id>/id, его Meta — рядом в id.json.
+type FS struct {
+	Root string
+}
+
+// NewFS создаёт FS в папке root, создавая её при необходимости.
+func NewFS(root string) (*FS, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("storage.NewFS: %w", err)
+	}
+	return &FS{Root: root}, nil
+}
+
+func (s *FS) path(id string) string {
+	return filepath.Join(s.Root, id[:2], id)
+}
+
+// Put пишет файл и Meta во временные файлы и переименовывает их, чтобы
+// читатели не увидели недописанный файл.
+func (s *FS) Put(ctx context.Context, id string, r io.Reader, size int64, meta Meta) error {
+	if !validID(id) {
+		return ErrInvalidID
+	}
+	path := s.path(id)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	metaJSON, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(path+".json", bytes.NewReader(metaJSON)); err != nil {
+		return err
+	}
+	return writeFile(path, r)
+}
+
+func (s *FS) Open(ctx context.Context, id string) (*Blob, error) {
+	if !validID(id) {
+		return nil, ErrInvalidID
+	}
+	path := s.path(id)
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	var meta Meta
+	metaJSON, err := os.ReadFile(path + ".json")
+	if err == nil {
+		err = json.Unmarshal(metaJSON, &meta)
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		f.Close()
+		return nil, err
+	}
+	return &Blob{ReadSeekCloser: f, ID: id, Size: info.Size(), ModTime: info.ModTime(), Meta: meta}, nil
+}
+
+func (s *FS) Delete(ctx context.Context, id string) error {
+	if !validID(id) {
+		return ErrInvalidID
+	}
+	path := s.path(id)
+	for _, p := range []string{path, path + ".json"} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *FS) Walk(ctx context.Context, fn func(id string) error) error {
+	return filepath.WalkDir(s.Root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || !validID(d.Name()) {
+			return nil // папки, .json и временные файлы
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(d.Name())
+	})
+}
+
+// writeFile атомарно записывает содержимое r в path.
+func writeFile(path string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func newTestFS(t *testing.T) *FS {
+	t.Helper()
+	s, err := NewFS(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestFSPutOpen(t *testing.T) {
+	ctx := context.Background()
+	s := newTestFS(t)
+	id := NewID()
+	meta := Meta{ContentType: "image/png", Filename: "cat.png"}
+
+	if err := s.Put(ctx, id, strings.NewReader("hello"), 5, meta); err != nil {
+		t.Fatal(err)
+	}
+	blob, err := s.Open(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blob.Close()
+	data, err := io.ReadAll(blob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "hello" || blob.Size != 5 || blob.ID != id || blob.Meta != meta {
+		t.Errorf("Open = %q, size %d, id %s, meta %+v", data, blob.Size, blob.ID, blob.Meta)
+	}
+
+	// повторный Put заменяет файл целиком
+	if err := s.Put(ctx, id, strings.NewReader("hi"), 2, meta); err != nil {
+		t.Fatal(err)
+	}
+	blob2, err := s.Open(ctx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer blob2.Close()
+	if data, _ := io.ReadAll(blob2); string(data) != "hi" {
+		t.Errorf("after overwrite Open = %q, want %q", data, "hi")
+	}
+
+	// временные файлы не остаются
+	tmp, _ := filepath.Glob(filepath.Join(s.Root, id[:2], "*.tmp"))
+	if len(tmp) != 0 {
+		t.Errorf("leftover temp files: %v", tmp)
+	}
+}
+
+func TestFSOpenWithoutMeta(t *testing.T) {
+	ctx := context.Background()
+	s := newTestFS(t)
+	id := NewID()
+	if err := s.Put(ctx, id, strings.NewReader("x"), 1, Meta{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(s.path(id) + ".json"); err != nil {
+		t.Fatal(err)
+	}
+	blob, err := s.Open(ctx, id)
+	if err != nil {
+		t.Fatalf("Open without meta: %v", err)
+	}
+	blob.Close()
+}
+
+func TestFSErrors(t *testing.T) {
+	ctx := context.Background()
+	s := newTestFS(t)
+	tests := []struct {
+		name string
+		id   string
+		want error
+	}{
+		{"missing", NewID(), ErrNotFound},
+		{"not hex", "zzzzzzzzzzzzzzzzzzzzzzzz", ErrInvalidID},
+		{"too short", "abcd", ErrInvalidID},
+		{"path traversal", "../../../../etc/passwd", ErrInvalidID},
+		{"empty", "", ErrInvalidID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := s.Open(ctx, tt.id); !errors.Is(err, tt.want) {
+				t.Errorf("Open error = %v, want %v", err, tt.want)
+			}
+			if tt.want == ErrInvalidID {
+				if err := s.Put(ctx, tt.id, strings.NewReader("x"), 1, Meta{}); !errors.Is(err, ErrInvalidID) {
+					t.Errorf("Put error = %v, want %v", err, ErrInvalidID)
+				}
+				if err := s.Delete(ctx, tt.id); !errors.Is(err, ErrInvalidID) {
+					t.Errorf("Delete error = %v, want %v", err, ErrInvalidID)
+				}
+			}
+		})
+	}
+}
+
+func TestFSDelete(t *testing.T) {
+	ctx := context.Background()
+	s := newTestFS(t)
+	id := NewID()
+	if err := s.Put(ctx, id, strings.NewReader("x"), 1, Meta{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ { // отсутствующий файл ошибкой не считается
+		if err := s.Delete(ctx, id); err != nil {
+			t.Fatalf("Delete #%d: %v", i+1, err)
+		}
+	}
+	if _, err := s.Open(ctx, id); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Open after Delete error = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := os.Stat(s.path(id) + ".json"); !os.IsNotExist(err) {
+		t.Errorf("meta file left behind: %v", err)
+	}
+}
+
+func TestFSWalk(t *testing.T) {
+	ctx := context.Background()
+	s := newTestFS(t)
+	var want []string
+	for i := 0; i < 3; i++ {
+		id := NewID()
+		if err := s.Put(ctx, id, strings.NewReader("x"), 1, Meta{}); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, id)
+	}
+	// посторонние файлы пропускаются
+	if err := os.WriteFile(filepath.Join(s.Root, "README"), []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	if err := s.Walk(ctx, func(id string) error {
+		got = append(got, id)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("Walk = %v, want %v", got, want)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := s.Walk(cancelled, func(string) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Errorf("Walk with cancelled ctx error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,197 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// GridFS хранит файлы в MongoDB GridFS; Meta лежит в metadata файла.
+type GridFS struct {
+	DB *mongo.Database
+}
+
+// NewGridFS создаёт GridFS поверх базы db.
+func NewGridFS(db *mongo.Database) *GridFS {
+	return &GridFS{DB: db}
+}
+
+// Put загружает файл. UploadFromStreamWithID не принимает ctx, поэтому отмена
+// проверяется при чтении каждого куска: ошибка источника прерывает загрузку,
+// и GridFS удаляет уже записанные куски.
+func (s *GridFS) Put(ctx context.Context, id string, r io.Reader, size int64, meta Meta) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	bucket, err := gridfs.NewBucket(s.DB)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := bucket.SetWriteDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	opts := options.GridFSUpload().SetMetadata(meta)
+	return bucket.UploadFromStreamWithID(objID, meta.Filename, ctxReader{ctx: ctx, r: r}, opts)
+}
+
+// ctxReader — io.Reader, который перестаёт читать после отмены ctx.
+type ctxReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (r ctxReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// Open открывает файл. У файлов, загруженных до появления metadata,
+// Meta.ContentType пуст (тип определит отдающий), а имя берётся из GridFS.
+func (s *GridFS) Open(ctx context.Context, id string) (*Blob, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidID
+	}
+	bucket, err := gridfs.NewBucket(s.DB)
+	if err != nil {
+		return nil, err
+	}
+
+	stream, err := bucket.OpenDownloadStream(objID)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file := stream.GetFile()
+	var meta Meta
+	if len(file.Metadata) > 0 {
+		if err := bson.Unmarshal(file.Metadata, &meta); err != nil {
+			stream.Close()
+			return nil, err
+		}
+	}
+	if meta.Filename == "" {
+		meta.Filename = file.Name
+	}
+	return &Blob{
+		ReadSeekCloser: &gridfsReader{bucket: bucket, id: objID, size: file.Length, stream: stream},
+		ID:             id,
+		Size:           file.Length,
+		ModTime:        file.UploadDate,
+		Meta:           meta,
+	}, nil
+}
+
+func (s *GridFS) Delete(ctx context.Context, id string) error {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return ErrInvalidID
+	}
+	bucket, err := gridfs.NewBucket(s.DB)
+	if err != nil {
+		return err
+	}
+	if err := bucket.DeleteContext(ctx, objID); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *GridFS) Walk(ctx context.Context, fn func(id string) error) error {
+	bucket, err := gridfs.NewBucket(s.DB)
+	if err != nil {
+		return err
+	}
+	cur, err := bucket.FindContext(ctx, bson.D{})
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+	for cur.Next(ctx) {
+		var file struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cur.Decode(&file); err != nil {
+			return err
+		}
+		if err := fn(file.ID.Hex()); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// gridfsReader — io.ReadSeeker поверх потока GridFS (нужен для Range-запросов).
+// Поток читается только вперёд, поэтому Seek лишь запоминает позицию: Read
+// догоняет её через Skip, а для перехода назад переоткрывает поток.
+type gridfsReader struct {
+	bucket    *gridfs.Bucket
+	id        primitive.ObjectID
+	size      int64
+	stream    *gridfs.DownloadStream
+	streamPos int64 // позиция, до которой дочитан stream
+	pos       int64 // позиция, запрошенная через Seek
+}
+
+func (r *gridfsReader) Read(p []byte) (int, error) {
+	if r.pos >= r.size {
+		return 0, io.EOF
+	}
+	if r.pos < r.streamPos {
+		stream, err := r.bucket.OpenDownloadStream(r.id)
+		if err != nil {
+			return 0, err
+		}
+		r.stream.Close()
+		r.stream, r.streamPos = stream, 0
+	}
+	if r.pos > r.streamPos {
+		n, err := r.stream.Skip(r.pos - r.streamPos)
+		r.streamPos += n
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.stream.Read(p)
+	r.streamPos += int64(n)
+	r.pos = r.streamPos
+	return n, err
+}
+
+func (r *gridfsReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.pos
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return 0, errors.New("gridfsReader.Seek: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("gridfsReader.Seek: negative position")
+	}
+	r.pos = offset
+	return offset, nil
+}
+
+func (r *gridfsReader) Close() error {
+	return r.stream.Close()
+}
@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3 хранит файлы в бакете S3-совместимого хранилища (AWS S3, MinIO, ...)
+// под ключом id. Meta лежит в Content-Type и user-metadata объекта.
+type S3 struct {
+	Client *minio.Client
+	Bucket string
+}
+
+// metaFilename — ключ user-metadata с исходным именем файла. Значение
+// экранируется: в заголовках S3 допустим только ASCII.
+const metaFilename = "Filename"
+
+// NewS3 подключается к cfg.S3Endpoint и проверяет, что бакет cfg.S3Bucket существует.
+func NewS3(ctx context.Context, cfg Config) (*S3, error) {
+	if cfg.S3Endpoint == "" || cfg.S3Bucket == "" {
+		return nil, errors.New("storage.NewS3: S3_ENDPOINT and S3_BUCKET must be set")
+	}
+	client, err := minio.New(cfg.S3Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.S3AccessKey, cfg.S3SecretKey, ""),
+		Secure: cfg.S3UseSSL,
+		Region: cfg.S3Region,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storage.NewS3: %w", err)
+	}
+	exists, err := client.BucketExists(ctx, cfg.S3Bucket)
+	if err != nil {
+		return nil, fmt.Errorf("storage.NewS3: %w", err)
+	}
+	if !exists {
+		return nil, fmt.Errorf("storage.NewS3: bucket %q does not exist", cfg.S3Bucket)
+	}
+	return &S3{Client: client, Bucket: cfg.S3Bucket}, nil
+}
+
+func (s *S3) Put(ctx context.Context, id string, r io.Reader, size int64, meta Meta) error {
+	if !validID(id) {
+		return ErrInvalidID
+	}
+	_, err := s.Client.PutObject(ctx, s.Bucket, id, r, size, minio.PutObjectOptions{
+		ContentType:  meta.ContentType,
+		UserMetadata: map[string]string{metaFilename: url.PathEscape(meta.Filename)},
+	})
+	return err
+}
+
+// Open открывает объект. minio.Object сам реализует Seek через Range-запросы.
+func (s *S3) Open(ctx context.Context, id string) (*Blob, error) {
+	if !validID(id) {
+		return nil, ErrInvalidID
+	}
+	obj, err := s.Client.GetObject(ctx, s.Bucket, id, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	meta := Meta{ContentType: info.ContentType}
+	if v := info.UserMetadata[metaFilename]; v != "" {
+		if meta.Filename, err = url.PathUnescape(v); err != nil {
+			meta.Filename = v
+		}
+	}
+	return &Blob{ReadSeekCloser: obj, ID: id, Size: info.Size, ModTime: info.LastModified, Meta: meta}, nil
+}
+
+func (s *S3) Delete(ctx context.Context, id string) error {
+	if !validID(id) {
+		return ErrInvalidID
+	}
+	// S3 не считает удаление отсутствующего объекта ошибкой.
+	return s.Client.RemoveObject(ctx, s.Bucket, id, minio.RemoveObjectOptions{})
+}
+
+func (s *S3) Walk(ctx context.Context, fn func(id string) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel() // останавливает листинг, если fn вернула ошибку
+	for obj := range s.Client.ListObjects(ctx, s.Bucket, minio.ListObjectsOptions{Recursive: true}) {
+		if obj.Err != nil {
+			return obj.Err
+		}
+		if !validID(obj.Key) {
+			continue
+		}
+		if err := fn(obj.Key); err != nil {
+			return err
+		}
+	}
+	return ctx.Err()
+}
@@ -0,0 +1,139 @@
+// Package storage хранит файлы фото. Бэкенд — GridFS, локальная папка или
+// S3-совместимое хранилище — выбирается конфигурацией (см. ConfigFromEnv).
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"listing-service/internal/mongo"
+)
+
+// Бэкенды хранилища.
+const (
+	BackendGridFS = "gridfs"
+	BackendFS     = "fs"
+	BackendS3     = "s3"
+)
+
+var (
+	// ErrNotFound возвращается Open, если файла нет.
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidID возвращается для id, который не выдан NewID.
+	ErrInvalidID = errors.New("invalid blob id")
+)
+
+// Meta — проверенный MIME-тип и исходное имя файла, под которыми он отдаётся клиенту.
+type Meta struct {
+	ContentType string `bson:"contentType" json:"contentType"`
+	Filename    string `bson:"filename" json:"filename"`
+}
+
+// Blob — открытый на чтение файл. Его нужно закрыть.
+type Blob struct {
+	io.ReadSeekCloser
+	ID      string
+	Size    int64
+	ModTime time.Time
+	Meta    Meta
+}
+
+// Store — хранилище файлов. id выдаёт NewID; он же хранится в Postgres,
+// поэтому при переносе между бэкендами (cmd/migrate-blobs) не меняется.
+type Store interface {
+	// Put сохраняет size байт из r под id.
+	Put(ctx context.Context, id string, r io.Reader, size int64, meta Meta) error
+	// Open открывает файл на потоковое чтение с произвольным доступом.
+	Open(ctx context.Context, id string) (*Blob, error)
+	// Delete удаляет файл. Отсутствующий файл ошибкой не считается.
+	Delete(ctx context.Context, id string) error
+	// Walk вызывает fn для id каждого файла хранилища.
+	Walk(ctx context.Context, fn func(id string) error) error
+}
+
+// NewID выдаёт id нового файла. Формат — ObjectID в hex, как у файлов,
+// загруженных в GridFS до появления Store.
+func NewID() string {
+	return primitive.NewObjectID().Hex()
+}
+
+// validID проверяет, что id похож на выданный NewID: бэкенды строят из него
+// пути и ключи, поэтому произвольные строки не допускаются.
+func validID(id string) bool {
+	_, err := hex.DecodeString(id)
+	return len(id) == 24 && err == nil
+}
+
+// Config — настройки хранилища.
+type Config struct {
+	Backend string // gridfs, fs или s3
+
+	MongoURI string // gridfs
+	MongoDB  string
+
+	Dir string // fs
+
+	S3Endpoint  string // s3
+	S3Bucket    string
+	S3Region    string
+	S3AccessKey string
+	S3SecretKey string
+	S3UseSSL    bool
+}
+
+// ConfigFromEnv читает Config из окружения:
+//
+//	PHOTO_STORAGE      gridfs (по умолчанию), fs или s3
+//	MONGO_URI          gridfs; база listingphotos
+//	PHOTO_STORAGE_DIR  fs; по умолчанию ./data/photos
+//	S3_ENDPOINT, S3_BUCKET, S3_REGION, S3_ACCESS_KEY, S3_SECRET_KEY,
+//	S3_USE_SSL         s3; S3_USE_SSL по умолчанию true
+func ConfigFromEnv() (Config, error) {
+	cfg := Config{
+		Backend:     os.Getenv("PHOTO_STORAGE"),
+		MongoURI:    os.Getenv("MONGO_URI"),
+		MongoDB:     "listingphotos",
+		Dir:         os.Getenv("PHOTO_STORAGE_DIR"),
+		S3Endpoint:  os.Getenv("S3_ENDPOINT"),
+		S3Bucket:    os.Getenv("S3_BUCKET"),
+		S3Region:    os.Getenv("S3_REGION"),
+		S3AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		S3SecretKey: os.Getenv("S3_SECRET_KEY"),
+		S3UseSSL:    true,
+	}
+	if cfg.Backend == "" {
+		cfg.Backend = BackendGridFS
+	}
+	if cfg.Dir == "" {
+		cfg.Dir = "./data/photos"
+	}
+	if v := os.Getenv("S3_USE_SSL"); v != "" {
+		useSSL, err := strconv.ParseBool(v)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid S3_USE_SSL: %q", v)
+		}
+		cfg.S3UseSSL = useSSL
+	}
+	return cfg, nil
+}
+
+// Open создаёт хранилище бэкенда cfg.Backend.
+func Open(ctx context.Context, cfg Config) (Store, error) {
+	switch cfg.Backend {
+	case BackendGridFS:
+		return NewGridFS(mongo.NewMongoClient(cfg.MongoURI).Database(cfg.MongoDB)), nil
+	case BackendFS:
+		return NewFS(cfg.Dir)
+	case BackendS3:
+		return NewS3(ctx, cfg)
+	}
+	return nil, fmt.Errorf("unknown photo storage backend %q (want gridfs, fs or s3)", cfg.Backend)
+}
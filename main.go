@@ -1,8 +1,11 @@
 package main
 
 import (
+	"context"
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/jmoiron/sqlx"
@@ -11,9 +14,9 @@ import (
 
 	"listing-service/internal/handler"
 	"listing-service/internal/middleware"
-	"listing-service/internal/mongo"
 	"listing-service/internal/repository"
 	"listing-service/internal/service"
+	"listing-service/internal/storage"
 )
 
 func main() {
@@ -37,54 +40,139 @@ func main() {
 	db.SetMaxIdleConns(5)
 
 	// ─── 4) Instantiate Repositories ──────────────────────────────────────────
-	listingRepo := repository.NewListingRepository(db)
-	reviewRepo := repository.NewReviewRepository(db)
+	ratingPrior, err := repository.ParseRatingPrior(os.Getenv("RATING_PRIOR_MEAN"), os.Getenv("RATING_PRIOR_WEIGHT"))
+	if err != nil {
+		log.Fatalf("❌ Invalid rating prior: %v", err)
+	}
+	listingRepo := repository.NewListingRepository(db, ratingPrior)
+	reviewRepo := repository.NewReviewRepository(db, ratingPrior)
+	listingEventRepo := repository.NewListingEventRepository(db)
+	listingPhotoRepo := repository.NewListingPhotoRepository(db)
 
-	// ─── 5) MongoDB Init (for photo upload) ───────────────────────────────────
-	mongoClient := mongo.NewMongoClient(os.Getenv("MONGO_URI"))
-	photoRepo := repository.NewPhotoRepository(mongoClient, "listingphotos")
+	// ─── 5) Photo storage (PHOTO_STORAGE=gridfs|fs|s3) ────────────────────────
+	storageCfg, err := storage.ConfigFromEnv()
+	if err != nil {
+		log.Fatalf("❌ Invalid photo storage config: %v", err)
+	}
+	photoStore, err := storage.Open(context.Background(), storageCfg)
+	if err != nil {
+		log.Fatalf("❌ Failed to open photo storage: %v", err)
+	}
+	log.Printf("✅ Photo storage: %s", storageCfg.Backend)
+	photoRepo := repository.NewPhotoRepository(photoStore)
 
 	// ─── 6) Instantiate Services ──────────────────────────────────────────────
-	reviewSvc := service.NewReviewService(reviewRepo, listingRepo)
+	photoLimits := service.PhotoLimits{MaxBytes: service.DefaultPhotoMaxBytes, MaxPixels: service.DefaultPhotoMaxPixels}
+	if v := os.Getenv("PHOTO_MAX_BYTES"); v != "" {
+		if photoLimits.MaxBytes, err = strconv.ParseInt(v, 10, 64); err != nil || photoLimits.MaxBytes < 1 {
+			log.Fatalf("❌ Invalid PHOTO_MAX_BYTES: %q", v)
+		}
+	}
+	if v := os.Getenv("PHOTO_MAX_PIXELS"); v != "" {
+		if photoLimits.MaxPixels, err = strconv.Atoi(v); err != nil || photoLimits.MaxPixels < 1 {
+			log.Fatalf("❌ Invalid PHOTO_MAX_PIXELS: %q", v)
+		}
+	}
+	reviewMaxPhotos := 5
+	if v := os.Getenv("REVIEW_MAX_PHOTOS"); v != "" {
+		if reviewMaxPhotos, err = strconv.Atoi(v); err != nil || reviewMaxPhotos < 0 {
+			log.Fatalf("❌ Invalid REVIEW_MAX_PHOTOS: %q", v)
+		}
+	}
+	reviewSvc := service.NewReviewService(reviewRepo, listingRepo, photoRepo, reviewMaxPhotos, photoLimits)
+	listingSvc := service.NewListingService(listingRepo, listingEventRepo)
+	listingMaxPhotos := 10
+	if v := os.Getenv("LISTING_MAX_PHOTOS"); v != "" {
+		if listingMaxPhotos, err = strconv.Atoi(v); err != nil || listingMaxPhotos < 1 {
+			log.Fatalf("❌ Invalid LISTING_MAX_PHOTOS: %q", v)
+		}
+	}
+	photoSvc := service.NewPhotoService(listingRepo, listingPhotoRepo, photoRepo, listingMaxPhotos, photoLimits)
+
+	// ─── 6.1) Retention job: purge soft-deleted listings ──────────────────────
+	retentionDays := 30
+	if v := os.Getenv("LISTING_RETENTION_DAYS"); v != "" {
+		if retentionDays, err = strconv.Atoi(v); err != nil || retentionDays < 0 {
+			log.Fatalf("❌ Invalid LISTING_RETENTION_DAYS: %q", v)
+		}
+	}
+	purgeInterval := time.Hour
+	if v := os.Getenv("LISTING_PURGE_INTERVAL"); v != "" {
+		if purgeInterval, err = time.ParseDuration(v); err != nil || purgeInterval <= 0 {
+			log.Fatalf("❌ Invalid LISTING_PURGE_INTERVAL: %q", v)
+		}
+	}
+	retentionJob := service.NewRetentionJob(listingRepo, listingEventRepo, photoRepo,
+		time.Duration(retentionDays)*24*time.Hour, purgeInterval)
+	go retentionJob.Run(context.Background())
 
 	// ─── 7) Instantiate Handlers ──────────────────────────────────────────────
-	listingHandler := &handler.ListingHandler{Repo: listingRepo}
+	listingHandler := &handler.ListingHandler{Repo: listingRepo, Service: listingSvc, Photos: photoSvc}
 	reviewHandler := handler.NewReviewHandler(reviewSvc)
 	photoHandler := handler.PhotoHandler{
 		Repo:        photoRepo,
 		ListingRepo: listingRepo, // теперь он точно инициализирован выше
+		Service:     photoSvc,
 	}
 
 	// ─── 8) Set up Gin router ────────────────────────────────────────────────
 	router := gin.Default()
 	api := router.Group("/api")
 
-	// ─── 9) Photo upload/download routes ─────────────────────────────────────
-	photoHandler.RegisterRoutes(api) // добавим до protected listing routes
-
-	// ─── 10) Nest all "/listings" routes ──────────────────────────────────────
+	// ─── 9) Nest all "/listings" routes ───────────────────────────────────────
 	listings := api.Group("/listings")
 	{
 		// Public
 		listings.GET("", listingHandler.GetApprovedListings)
-		listings.GET("/:id", listingHandler.GetListingByID)
-		listings.GET("/:id/reviews", reviewHandler.GetReviews)
+		listings.GET("/facets", listingHandler.GetFacets)
+		listings.GET("/:id", middleware.OptionalJWTAuth(), listingHandler.GetListingByID)
+		listings.GET("/:id/reviews", middleware.OptionalJWTAuth(), reviewHandler.GetReviews)
+		listings.GET("/:id/photo", middleware.OptionalJWTAuth(), photoHandler.DownloadPhoto)
+		listings.GET("/:id/photos", middleware.OptionalJWTAuth(), photoHandler.ListPhotos)
+		listings.GET("/:id/photos/:photoId", middleware.OptionalJWTAuth(), photoHandler.DownloadGalleryPhoto)
+		listings.GET("/:id/reviews/:reviewId/photos/:photoId", middleware.OptionalJWTAuth(), reviewHandler.DownloadPhoto)
 
-		// Protected
+		// Any authenticated user; ownership is checked per listing
 		protected := listings.Group("")
 		protected.Use(middleware.JWTAuthMiddleware())
 		{
 			protected.POST("", listingHandler.CreateListing)
 			protected.PUT("/:id", listingHandler.UpdateListing)
+			protected.PUT("/:id/status", listingHandler.ChangeStatus)
 			protected.DELETE("/:id", listingHandler.DeleteListing)
-			protected.GET("/admin/pending", listingHandler.GetPending)
-			protected.PUT("/admin/:id/approve", listingHandler.Approve)
-			protected.PUT("/admin/:id/reject", listingHandler.Reject)
+			protected.POST("/:id/photo", photoHandler.UploadPhoto)
+			protected.POST("/:id/photos", photoHandler.AddPhoto)
+			protected.PUT("/:id/photos/order", photoHandler.ReorderPhotos)
+			protected.PUT("/:id/photos/:photoId/cover", photoHandler.SetCover)
+			protected.DELETE("/:id/photos/:photoId", photoHandler.DeletePhoto)
 			protected.POST("/:id/reviews", reviewHandler.CreateReview)
+			protected.PUT("/:id/reviews/:reviewId", reviewHandler.UpdateReview)
+			protected.DELETE("/:id/reviews/:reviewId", reviewHandler.DeleteReview)
+			protected.PUT("/:id/reviews/:reviewId/reply", reviewHandler.SetReply)
+			protected.DELETE("/:id/reviews/:reviewId/reply", reviewHandler.DeleteReply)
+			protected.PUT("/:id/reviews/:reviewId/helpful", reviewHandler.VoteHelpful)
+			protected.DELETE("/:id/reviews/:reviewId/helpful", reviewHandler.UnvoteHelpful)
+			protected.POST("/:id/reviews/:reviewId/report", reviewHandler.ReportReview)
+			protected.POST("/:id/reviews/:reviewId/photos", reviewHandler.UploadPhoto)
+			protected.DELETE("/:id/reviews/:reviewId/photos/:photoId", reviewHandler.DeletePhoto)
+
+			// Admin only
+			admin := protected.Group("/admin")
+			admin.Use(middleware.RequireAdmin())
+			{
+				admin.GET("/pending", listingHandler.GetPending)
+				admin.PUT("/:id/approve", listingHandler.Approve)
+				admin.PUT("/:id/reject", listingHandler.Reject)
+				admin.PUT("/:id/restore", listingHandler.Restore)
+				admin.GET("/:id/history", listingHandler.GetHistory)
+				admin.GET("/reviews/reported", reviewHandler.GetReported)
+				admin.PUT("/reviews/:reviewId/hide", reviewHandler.HideReview)
+				admin.PUT("/reviews/:reviewId/restore", reviewHandler.RestoreReview)
+			}
 		}
 	}
 
-	// ─── 11) Start the server ─────────────────────────────────────────────────
+	// ─── 10) Start the server ─────────────────────────────────────────────────
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8081"
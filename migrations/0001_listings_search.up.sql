@@ -0,0 +1,15 @@
+-- Полнотекстовый поиск по заголовку и описанию объявления.
+-- Вектор хранится в generated-колонке, поэтому Postgres сам поддерживает его
+-- актуальным при каждом INSERT/UPDATE. Заголовок весит больше описания (A > B),
+-- русская и английская конфигурации объединяются, чтобы работали обе морфологии.
+ALTER TABLE listings
+    ADD COLUMN IF NOT EXISTS search_vector tsvector
+        GENERATED ALWAYS AS (
+            setweight(to_tsvector('russian', coalesce(title, '')), 'A') ||
+            setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+            setweight(to_tsvector('russian', coalesce(description, '')), 'B') ||
+            setweight(to_tsvector('english', coalesce(description, '')), 'B')
+        ) STORED;
+
+CREATE INDEX IF NOT EXISTS listings_search_vector_idx
+    ON listings USING GIN (search_vector);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS listings_lat_lng_idx;
+
+ALTER TABLE listings
+    DROP CONSTRAINT IF EXISTS listings_coordinates_pair,
+    DROP CONSTRAINT IF EXISTS listings_longitude_range,
+    DROP CONSTRAINT IF EXISTS listings_latitude_range,
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS latitude;
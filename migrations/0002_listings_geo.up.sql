@@ -0,0 +1,15 @@
+-- Координаты объявления для поиска в радиусе.
+-- Обе колонки nullable: старые объявления координат не имеют и в гео-выдачу не попадают.
+ALTER TABLE listings
+    ADD COLUMN IF NOT EXISTS latitude  DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
+
+ALTER TABLE listings
+    ADD CONSTRAINT listings_latitude_range  CHECK (latitude BETWEEN -90 AND 90),
+    ADD CONSTRAINT listings_longitude_range CHECK (longitude BETWEEN -180 AND 180),
+    ADD CONSTRAINT listings_coordinates_pair CHECK ((latitude IS NULL) = (longitude IS NULL));
+
+-- Индекс под предварительный отбор по bounding box перед расчётом haversine.
+CREATE INDEX IF NOT EXISTS listings_lat_lng_idx
+    ON listings (latitude, longitude)
+    WHERE latitude IS NOT NULL;
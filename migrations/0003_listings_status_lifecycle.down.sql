@@ -0,0 +1,3 @@
+ALTER TABLE listings DROP CONSTRAINT IF EXISTS listings_status_check;
+
+ALTER TABLE listings ALTER COLUMN status DROP DEFAULT;
@@ -0,0 +1,12 @@
+-- Жизненный цикл объявления: статус может принимать только известные значения.
+-- Переходы между статусами проверяются в ListingService.
+ALTER TABLE listings ALTER COLUMN status SET DEFAULT 'pending';
+
+-- Неизвестные статусы, которые клиенты могли записать раньше, отправляем на модерацию.
+UPDATE listings
+SET status = 'pending'
+WHERE status NOT IN ('draft', 'pending', 'approved', 'rejected', 'archived', 'sold', 'expired');
+
+ALTER TABLE listings
+    ADD CONSTRAINT listings_status_check
+        CHECK (status IN ('draft', 'pending', 'approved', 'rejected', 'archived', 'sold', 'expired'));
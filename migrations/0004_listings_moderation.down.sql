@@ -0,0 +1,5 @@
+ALTER TABLE listings
+    DROP COLUMN IF EXISTS moderated_at,
+    DROP COLUMN IF EXISTS moderated_by,
+    DROP COLUMN IF EXISTS moderation_note,
+    DROP COLUMN IF EXISTS moderation_reason;
@@ -0,0 +1,7 @@
+-- Результат последней модерации: причина отклонения, комментарий модератора,
+-- кто и когда принял решение. Показывается владельцу объявления.
+ALTER TABLE listings
+    ADD COLUMN IF NOT EXISTS moderation_reason TEXT,
+    ADD COLUMN IF NOT EXISTS moderation_note   TEXT,
+    ADD COLUMN IF NOT EXISTS moderated_by      TEXT,
+    ADD COLUMN IF NOT EXISTS moderated_at      TIMESTAMPTZ;
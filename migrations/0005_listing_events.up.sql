@@ -0,0 +1,14 @@
+-- История изменений объявлений: кто, что и когда сделал.
+-- Внешнего ключа на listings нет намеренно: история переживает удаление объявления.
+CREATE TABLE IF NOT EXISTS listing_events (
+    id         BIGSERIAL PRIMARY KEY,
+    listing_id TEXT        NOT NULL,
+    actor_id   TEXT        NOT NULL,
+    action     TEXT        NOT NULL,
+    old_value  JSONB,
+    new_value  JSONB,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS listing_events_listing_idx
+    ON listing_events (listing_id, created_at DESC, id DESC);
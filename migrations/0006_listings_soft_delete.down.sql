@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS listings_deleted_at_idx;
+
+ALTER TABLE listings DROP COLUMN IF EXISTS deleted_at;
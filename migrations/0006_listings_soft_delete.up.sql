@@ -0,0 +1,8 @@
+-- Мягкое удаление объявлений. Строки с deleted_at не видны ни в одном запросе
+-- чтения; RetentionJob окончательно удаляет их (с отзывами и фото) по истечении
+-- LISTING_RETENTION_DAYS.
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS listings_deleted_at_idx
+    ON listings (deleted_at)
+    WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS reviews_listing_user_uniq;
+
+ALTER TABLE reviews DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,20 @@
+-- Один отзыв пользователя на объявление.
+-- Перед созданием уникального индекса оставляем только самый свежий отзыв
+-- каждого автора и пересчитываем средние оценки.
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+
+DELETE FROM reviews r
+USING (
+    SELECT id,
+           row_number() OVER (PARTITION BY listing_id, user_id ORDER BY created_at DESC, id DESC) AS rn
+    FROM reviews
+) ranked
+WHERE r.id = ranked.id AND ranked.rn > 1;
+
+UPDATE listings l
+SET average_rating = COALESCE((
+    SELECT AVG(rating)::numeric(3,2) FROM reviews WHERE listing_id = l.id
+), 0);
+
+CREATE UNIQUE INDEX IF NOT EXISTS reviews_listing_user_uniq
+    ON reviews (listing_id, user_id);
@@ -0,0 +1,4 @@
+ALTER TABLE reviews
+    DROP COLUMN IF EXISTS reply_updated_at,
+    DROP COLUMN IF EXISTS reply_created_at,
+    DROP COLUMN IF EXISTS reply_text;
@@ -0,0 +1,5 @@
+-- Публичный ответ владельца объявления на отзыв (не больше одного на отзыв).
+ALTER TABLE reviews
+    ADD COLUMN IF NOT EXISTS reply_text       TEXT,
+    ADD COLUMN IF NOT EXISTS reply_created_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS reply_updated_at TIMESTAMPTZ;
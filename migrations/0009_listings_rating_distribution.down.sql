@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS listings_review_count_idx;
+DROP INDEX IF EXISTS listings_average_rating_idx;
+
+ALTER TABLE listings
+    DROP COLUMN IF EXISTS rating_5,
+    DROP COLUMN IF EXISTS rating_4,
+    DROP COLUMN IF EXISTS rating_3,
+    DROP COLUMN IF EXISTS rating_2,
+    DROP COLUMN IF EXISTS rating_1,
+    DROP COLUMN IF EXISTS review_count;
@@ -0,0 +1,39 @@
+-- Агрегаты отзывов на объявлении: число отзывов и гистограмма по звёздам.
+-- Поддерживаются вместе с average_rating: ReviewRepository.adjustAggregates
+-- обновляет их при каждом изменении отзыва, RecalcAll пересчитывает с нуля.
+ALTER TABLE listings
+    ADD COLUMN IF NOT EXISTS review_count INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rating_1     INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rating_2     INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rating_3     INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rating_4     INT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rating_5     INT NOT NULL DEFAULT 0;
+
+UPDATE listings l
+SET review_count = a.review_count,
+    rating_1     = a.rating_1,
+    rating_2     = a.rating_2,
+    rating_3     = a.rating_3,
+    rating_4     = a.rating_4,
+    rating_5     = a.rating_5
+FROM (
+    SELECT listing_id,
+           COUNT(*)                          AS review_count,
+           COUNT(*) FILTER (WHERE rating = 1) AS rating_1,
+           COUNT(*) FILTER (WHERE rating = 2) AS rating_2,
+           COUNT(*) FILTER (WHERE rating = 3) AS rating_3,
+           COUNT(*) FILTER (WHERE rating = 4) AS rating_4,
+           COUNT(*) FILTER (WHERE rating = 5) AS rating_5
+    FROM reviews
+    GROUP BY listing_id
+) a
+WHERE a.listing_id = l.id;
+
+-- Сортировки sort=rating и sort=reviews в публичной выдаче.
+CREATE INDEX IF NOT EXISTS listings_average_rating_idx
+    ON listings (average_rating DESC, id DESC)
+    WHERE deleted_at IS NULL AND status = 'approved';
+
+CREATE INDEX IF NOT EXISTS listings_review_count_idx
+    ON listings (review_count DESC, id DESC)
+    WHERE deleted_at IS NULL AND status = 'approved';
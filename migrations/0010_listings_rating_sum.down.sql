@@ -0,0 +1 @@
+ALTER TABLE listings DROP COLUMN IF EXISTS rating_sum;
@@ -0,0 +1,13 @@
+-- Сумма оценок для инкрементального пересчёта average_rating: вставка, правка
+-- и удаление отзыва меняют счётчики в той же транзакции, без AVG по всем отзывам.
+-- Если агрегаты разошлись с reviews, их восстанавливает cmd/repair-ratings.
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS rating_sum BIGINT NOT NULL DEFAULT 0;
+
+UPDATE listings l
+SET rating_sum = a.rating_sum
+FROM (
+    SELECT listing_id, SUM(rating) AS rating_sum
+    FROM reviews
+    GROUP BY listing_id
+) a
+WHERE a.listing_id = l.id;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS listings_rating_score_idx;
+
+CREATE INDEX IF NOT EXISTS listings_average_rating_idx
+    ON listings (average_rating DESC, id DESC)
+    WHERE deleted_at IS NULL AND status = 'approved';
+
+ALTER TABLE listings DROP COLUMN IF EXISTS rating_score;
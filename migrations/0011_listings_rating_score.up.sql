@@ -0,0 +1,15 @@
+-- Байесовская оценка объявления для sort=rating:
+--   (weight * mean + rating_sum) / (weight + review_count),
+-- у объявлений без отзывов это просто mean. Заполняется с априорными значениями
+-- по умолчанию (mean = 3.5, weight = 10); при других RATING_PRIOR_MEAN /
+-- RATING_PRIOR_WEIGHT после миграции нужно запустить cmd/repair-ratings.
+ALTER TABLE listings ADD COLUMN IF NOT EXISTS rating_score DOUBLE PRECISION NOT NULL DEFAULT 3.5;
+
+UPDATE listings
+SET rating_score = (10 * 3.5::float8 + rating_sum) / (10 + review_count);
+
+DROP INDEX IF EXISTS listings_average_rating_idx;
+
+CREATE INDEX IF NOT EXISTS listings_rating_score_idx
+    ON listings (rating_score DESC, id DESC)
+    WHERE deleted_at IS NULL AND status = 'approved';
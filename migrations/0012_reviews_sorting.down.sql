@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS reviews_listing_helpful_idx;
+DROP INDEX IF EXISTS reviews_listing_rating_idx;
+DROP INDEX IF EXISTS reviews_listing_created_idx;
+
+ALTER TABLE reviews DROP COLUMN IF EXISTS helpful_count;
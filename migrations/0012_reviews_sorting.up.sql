@@ -0,0 +1,12 @@
+-- Сортировки отзывов объявления: newest, highest/lowest (по оценке) и
+-- most_helpful (по числу отметок «полезно»).
+ALTER TABLE reviews ADD COLUMN IF NOT EXISTS helpful_count INT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS reviews_listing_created_idx
+    ON reviews (listing_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS reviews_listing_rating_idx
+    ON reviews (listing_id, rating, id);
+
+CREATE INDEX IF NOT EXISTS reviews_listing_helpful_idx
+    ON reviews (listing_id, helpful_count DESC, id DESC);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS review_votes;
+
+UPDATE reviews SET helpful_count = 0;
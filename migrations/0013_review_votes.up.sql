@@ -0,0 +1,8 @@
+-- Отметки «полезно» на отзывах: не больше одной от пользователя на отзыв.
+-- reviews.helpful_count меняется в той же транзакции, что и эта таблица.
+CREATE TABLE IF NOT EXISTS review_votes (
+    review_id  TEXT        NOT NULL,
+    user_id    TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (review_id, user_id)
+);
@@ -0,0 +1,6 @@
+-- После отката скрытые отзывы снова учитываются: запустите cmd/repair-ratings.
+DROP TABLE IF EXISTS review_reports;
+
+ALTER TABLE reviews
+    DROP COLUMN IF EXISTS hidden_by,
+    DROP COLUMN IF EXISTS hidden_at;
@@ -0,0 +1,26 @@
+-- Жалобы на отзывы и скрытие отзывов администратором. Скрытые отзывы не
+-- попадают в выдачу и не учитываются в агрегатах рейтинга объявления.
+ALTER TABLE reviews
+    ADD COLUMN IF NOT EXISTS hidden_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS hidden_by TEXT;
+
+CREATE TABLE IF NOT EXISTS review_reports (
+    id          BIGSERIAL PRIMARY KEY,
+    review_id   TEXT        NOT NULL,
+    reporter_id TEXT        NOT NULL,
+    reason      TEXT        NOT NULL,
+    note        TEXT,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT now(),
+    resolved_at TIMESTAMPTZ,
+    resolved_by TEXT
+);
+
+-- Одна открытая жалоба от пользователя на отзыв; после решения админа можно
+-- пожаловаться снова.
+CREATE UNIQUE INDEX IF NOT EXISTS review_reports_open_uniq
+    ON review_reports (review_id, reporter_id)
+    WHERE resolved_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS review_reports_open_idx
+    ON review_reports (review_id)
+    WHERE resolved_at IS NULL;
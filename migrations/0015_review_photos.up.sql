@@ -0,0 +1,11 @@
+-- Фото к отзывам (не больше REVIEW_MAX_PHOTOS на отзыв). Сами файлы лежат
+-- в GridFS, здесь — ссылки на них.
+CREATE TABLE IF NOT EXISTS review_photos (
+    id         BIGSERIAL PRIMARY KEY,
+    review_id  TEXT        NOT NULL,
+    file_id    TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS review_photos_review_idx
+    ON review_photos (review_id, id);
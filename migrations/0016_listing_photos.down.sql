@@ -0,0 +1,3 @@
+-- listings.photo_file_id всегда указывает на обложку, поэтому после отката
+-- у объявления остаётся она; остальные файлы галереи в GridFS осиротеют.
+DROP TABLE IF EXISTS listing_photos;
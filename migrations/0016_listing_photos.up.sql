@@ -0,0 +1,25 @@
+-- Галерея фото объявления (не больше LISTING_MAX_PHOTOS). Файл обложки
+-- продолжает храниться в listings.photo_file_id для GET /api/listings/:id/photo.
+CREATE TABLE IF NOT EXISTS listing_photos (
+    id         BIGSERIAL PRIMARY KEY,
+    listing_id TEXT        NOT NULL,
+    file_id    TEXT        NOT NULL,
+    position   INT         NOT NULL,
+    is_cover   BOOLEAN     NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS listing_photos_listing_idx
+    ON listing_photos (listing_id, position, id);
+
+-- Ровно одна обложка на объявление.
+CREATE UNIQUE INDEX IF NOT EXISTS listing_photos_cover_uniq
+    ON listing_photos (listing_id)
+    WHERE is_cover;
+
+-- Существующее единственное фото становится обложкой галереи.
+INSERT INTO listing_photos (listing_id, file_id, position, is_cover)
+SELECT l.id::text, l.photo_file_id, 0, TRUE
+FROM listings l
+WHERE COALESCE(l.photo_file_id, '') <> ''
+  AND NOT EXISTS (SELECT 1 FROM listing_photos p WHERE p.listing_id = l.id::text);
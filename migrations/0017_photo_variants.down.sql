@@ -0,0 +1,10 @@
+-- Файлы вариантов в GridFS после отката осиротеют.
+ALTER TABLE review_photos
+    DROP COLUMN IF EXISTS large_file_id,
+    DROP COLUMN IF EXISTS medium_file_id,
+    DROP COLUMN IF EXISTS thumb_file_id;
+
+ALTER TABLE listing_photos
+    DROP COLUMN IF EXISTS large_file_id,
+    DROP COLUMN IF EXISTS medium_file_id,
+    DROP COLUMN IF EXISTS thumb_file_id;
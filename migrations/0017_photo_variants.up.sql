@@ -0,0 +1,12 @@
+-- Уменьшенные копии фото (thumb/medium/large). NULL — вариант не создавался:
+-- оригинал не больше нужного размера, не распознан как изображение или был
+-- загружен до появления вариантов; тогда вместо варианта отдаётся оригинал.
+ALTER TABLE listing_photos
+    ADD COLUMN IF NOT EXISTS thumb_file_id  TEXT,
+    ADD COLUMN IF NOT EXISTS medium_file_id TEXT,
+    ADD COLUMN IF NOT EXISTS large_file_id  TEXT;
+
+ALTER TABLE review_photos
+    ADD COLUMN IF NOT EXISTS thumb_file_id  TEXT,
+    ADD COLUMN IF NOT EXISTS medium_file_id TEXT,
+    ADD COLUMN IF NOT EXISTS large_file_id  TEXT;